@@ -4,7 +4,9 @@ import (
 	"log"
 	"sermorpheus-engine-test/internal/config"
 	"sermorpheus-engine-test/internal/handlers"
+	"sermorpheus-engine-test/internal/middleware"
 	"sermorpheus-engine-test/internal/services"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -17,25 +19,108 @@ func main() {
 
 	cfg := config.Load()
 
-	dbService := services.NewDatabaseService(cfg.DatabaseURL)
+	chainRegistry, err := services.NewChainRegistry(cfg)
+	if err != nil {
+		log.Fatal("Invalid chain configuration:", err)
+	}
+
+	dbService := services.NewDatabaseService(cfg.DatabaseURL, chainRegistry)
 	defer dbService.Close()
 
+	authService, err := services.NewAuthService(dbService.DB, cfg.AdminAPIKey, cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTTokenTTL)
+	if err != nil {
+		log.Fatal("Invalid auth configuration:", err)
+	}
 	eventService := services.NewEventService(dbService.DB)
 	customerService := services.NewCustomerService(dbService.DB)
-	rateService := services.NewRateService(dbService.DB)
-	blockchainService := services.NewBlockchainService(dbService.DB, cfg)
+	ratePipeline, err := services.NewRatePipeline(cfg, chainRegistry)
+	if err != nil {
+		log.Fatal("Invalid rate configuration:", err)
+	}
+	rateService := services.NewRateService(dbService.DB, ratePipeline, cfg.RateCacheMaxAge, cfg.RateFetchTimeout, cfg.RateOverrideMaxDuration)
+	rateService.StartRefresher(cfg.RateRefreshInterval)
+	rateService.StartOverrideSync(5 * time.Second)
+	rateService.StartRetentionJob(time.Hour, cfg.RateRawRetention, cfg.RateCandleRetention)
+	webhookService := services.NewWebhookService(dbService.DB, cfg.WebhookTimeout, cfg.WebhookMaxAttempts, cfg.WebhookRetryBase)
+	webhookService.StartDeliveryWorker(5 * time.Second)
+	var emailSender *services.SMTPSender
+	if cfg.EmailEnabled {
+		emailSender, err = services.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPTLS, cfg.SMTPTimeout, cfg.EmailFrom)
+		if err != nil {
+			log.Fatal("Invalid SMTP configuration:", err)
+		}
+	}
+	notificationService, err := services.NewNotificationService(dbService.DB, chainRegistry, emailSender, cfg.EmailDefaultLocale, cfg.EmailTimezone, cfg.EmailMaxAttempts, cfg.EmailRetryBase)
+	if err != nil {
+		log.Fatal("Invalid email configuration:", err)
+	}
+	if cfg.EmailEnabled {
+		notificationService.StartDeliveryWorker(5 * time.Second)
+	}
+	outboxSinks, err := services.NewOutboxSinks(cfg, webhookService, notificationService)
+	if err != nil {
+		log.Fatal("Invalid outbox configuration:", err)
+	}
+	outboxService := services.NewOutboxService(dbService.DB, outboxSinks)
+	outboxService.StartRelayWorker(time.Second)
+	outboxService.StartRetentionJob(time.Hour, cfg.OutboxRetention)
+	blockchainService := services.NewBlockchainService(dbService.DB, chainRegistry, cfg.RPCTimeout, cfg.RPCMaxBlockLag)
+	blockchainService.StartWatchers(10*time.Second, cfg.RPCHealthCheckInterval)
+	seatService := services.NewSeatService(dbService.DB)
+	waitlistService := services.NewWaitlistService(dbService.DB, eventService, cfg.WaitlistOfferTTL)
+	var uniqueAmounts *services.UniqueAmountAllocator
+	switch cfg.PaymentMode {
+	case services.PaymentModeAddress:
+	case services.PaymentModeUniqueAmount:
+		uniqueAmounts, err = services.NewUniqueAmountAllocator(chainRegistry, cfg.UniqueAmountPlaces, cfg.UniqueAmountMaxSuffix, cfg.UniqueAmountGrace)
+		if err != nil {
+			log.Fatal("Invalid unique amount configuration:", err)
+		}
+	default:
+		log.Fatalf("Unknown PAYMENT_MODE %q", cfg.PaymentMode)
+	}
+	feeService := services.NewFeeService(dbService.DB, chainRegistry, cfg.PlatformFeePercent)
+	promoService := services.NewPromoService(dbService.DB)
+	refundService := services.NewRefundService(dbService.DB)
+	quoteService := services.NewQuoteService(dbService.DB, eventService, rateService, chainRegistry, feeService, promoService, cfg.QuoteTTL)
 	transactionService := services.NewTransactionService(
 		dbService.DB,
 		eventService,
 		rateService,
 		blockchainService,
-		cfg.PlatformFeePercent,
+		seatService,
+		waitlistService,
+		quoteService,
+		chainRegistry,
+		uniqueAmounts,
+		feeService,
+		promoService,
+		cfg.PaymentWindow,
+		cfg.PaymentReminders,
 	)
+	transactionService.StartExpiryWorker(time.Minute)
+	transactionService.StartReminderWorker(15 * time.Second)
+	paymentStatusHub := services.NewPaymentStatusHub(cfg.DatabaseURL)
+	paymentStatusHub.Start()
+	paymentCheckService := services.NewPaymentCheckService(dbService.DB, blockchainService, cfg.PaymentCheckTimeout)
+	waitlistService.StartOfferExpiryWorker(time.Minute)
 
 	eventHandler := handlers.NewEventHandler(eventService)
 	customerHandler := handlers.NewCustomerHandler(customerService)
-	transactionHandler := handlers.NewTransactionHandler(transactionService, customerService, blockchainService)
+	transactionHandler := handlers.NewTransactionHandler(transactionService, customerService, paymentCheckService, paymentStatusHub, cfg.PaymentCheckWait)
 	rateHandler := handlers.NewRateHandler(rateService)
+	seatHandler := handlers.NewSeatHandler(seatService)
+	waitlistHandler := handlers.NewWaitlistHandler(waitlistService, customerService)
+	quoteHandler := handlers.NewQuoteHandler(quoteService)
+	chainHandler := handlers.NewChainHandler(chainRegistry, rateService, blockchainService)
+	feeHandler := handlers.NewFeeHandler(feeService)
+	promoHandler := handlers.NewPromoHandler(promoService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	outboxHandler := handlers.NewOutboxHandler(outboxService)
+	refundHandler := handlers.NewRefundHandler(refundService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	ticketHandler := handlers.NewTicketHandler(transactionService)
+	authHandler := handlers.NewAuthHandler(authService)
 
 	r := gin.Default()
 
@@ -46,31 +131,106 @@ func main() {
 		})
 	})
 
-	v1 := r.Group("/api/v1")
+	// Admins pass every role check; organizers are further limited to their
+	// own events, and customers to their own bookings, by the handlers.
+	requireAdmin := middleware.RequireRole(services.RoleAdmin)
+	requireOrganizer := middleware.RequireRole(services.RoleOrganizer)
+	requireCustomer := middleware.RequireRole(services.RoleCustomer)
+	requireAnyRole := middleware.RequireRole(services.RoleOrganizer, services.RoleGateStaff, services.RoleCustomer)
+	requireEventOrganizer := middleware.RequireEventOrganizer(eventService)
+
+	v1 := r.Group("/api/v1", middleware.Authenticate(authService))
 	{
+		v1.GET("/auth/me", requireAnyRole, authHandler.Me)
+
 		events := v1.Group("/events")
 		{
-			events.POST("", eventHandler.CreateEvent)
+			events.POST("", requireOrganizer, eventHandler.CreateEvent)
 			events.GET("", eventHandler.GetEvents)
 			events.GET("/:id", eventHandler.GetEventByID)
+			events.PATCH("/:id", requireOrganizer, requireEventOrganizer, eventHandler.UpdateEvent)
+			events.DELETE("/:id", requireOrganizer, requireEventOrganizer, eventHandler.DeleteEvent)
+			events.POST("/:id/cancel", requireOrganizer, requireEventOrganizer, eventHandler.CancelEvent)
+			events.GET("/:id/seats", seatHandler.GetSeats)
+			events.PUT("/:id/seats", requireOrganizer, requireEventOrganizer, seatHandler.ConfigureLayout)
+			events.POST("/:id/waitlist", requireCustomer, waitlistHandler.JoinWaitlist)
 		}
 
-		customers := v1.Group("/customers")
+		waitlist := v1.Group("/waitlist", middleware.RequireRole(services.RoleOrganizer, services.RoleCustomer))
+		{
+			waitlist.GET("/:id", waitlistHandler.GetEntry)
+			waitlist.DELETE("/:id", waitlistHandler.LeaveWaitlist)
+		}
+
+		customers := v1.Group("/customers", requireCustomer)
 		{
 			customers.GET("/:id", customerHandler.GetCustomer)
 		}
 
 		transactions := v1.Group("/transactions")
 		{
-			transactions.POST("", transactionHandler.CreateTransaction)
-			transactions.GET("/:id", transactionHandler.GetTransaction)
-			transactions.POST("/:id/confirm", transactionHandler.ConfirmPayment)
-			transactions.POST("/:id/check", transactionHandler.CheckPayment)
+			transactions.POST("", requireCustomer, transactionHandler.CreateTransaction)
+			transactions.GET("/:id", requireAnyRole, transactionHandler.GetTransaction)
+			transactions.POST("/:id/confirm", requireAdmin, transactionHandler.ConfirmPayment)
+			transactions.POST("/:id/check", requireAnyRole, transactionHandler.CheckPayment)
+			transactions.GET("/:id/checks/:check_id", requireAnyRole, transactionHandler.GetPaymentCheck)
+			transactions.GET("/:id/events", middleware.QueryToken(authService), requireAnyRole, transactionHandler.StreamEvents)
+		}
+
+		tickets := v1.Group("/tickets", requireAnyRole)
+		{
+			tickets.GET("/:code", ticketHandler.GetTicket)
+		}
+
+		quotes := v1.Group("/quotes")
+		{
+			quotes.POST("", quoteHandler.CreateQuote)
+			quotes.GET("/:id", quoteHandler.GetQuote)
 		}
 
 		rates := v1.Group("/rates")
 		{
 			rates.GET("/current", rateHandler.GetCurrentRate)
+			rates.GET("/history", rateHandler.GetRateHistory)
+		}
+
+		v1.GET("/chains", chainHandler.GetChains)
+		v1.GET("/tokens", chainHandler.GetTokens)
+
+		admin := v1.Group("/admin", requireAdmin)
+		{
+			admin.GET("/rates/override", rateHandler.GetOverride)
+			admin.POST("/rates/override", rateHandler.SetOverride)
+			admin.DELETE("/rates/override", rateHandler.RevokeOverride)
+			admin.GET("/rates/audit", rateHandler.GetAuditLog)
+			admin.GET("/chains/rpc", chainHandler.GetRPCStatus)
+			admin.GET("/fees", feeHandler.ListFeeRules)
+			admin.POST("/fees", feeHandler.CreateFeeRule)
+			admin.PATCH("/fees/:id", feeHandler.UpdateFeeRule)
+			admin.DELETE("/fees/:id", feeHandler.DisableFeeRule)
+			admin.GET("/promos", promoHandler.ListPromoCodes)
+			admin.POST("/promos", promoHandler.CreatePromoCode)
+			admin.PATCH("/promos/:id", promoHandler.UpdatePromoCode)
+			admin.DELETE("/promos/:id", promoHandler.DisablePromoCode)
+			admin.GET("/promos/:id/redemptions", promoHandler.GetRedemptions)
+			admin.GET("/webhooks", webhookHandler.ListWebhooks)
+			admin.POST("/webhooks", webhookHandler.CreateWebhook)
+			admin.PATCH("/webhooks/:id", webhookHandler.UpdateWebhook)
+			admin.DELETE("/webhooks/:id", webhookHandler.DisableWebhook)
+			admin.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+			admin.GET("/webhook-deliveries/:id", webhookHandler.GetDelivery)
+			admin.POST("/webhook-deliveries/:id/redeliver", webhookHandler.Redeliver)
+			admin.GET("/refunds", refundHandler.ListRefunds)
+			admin.POST("/refunds/:id/complete", refundHandler.CompleteRefund)
+			admin.GET("/outbox", outboxHandler.ListEvents)
+			admin.POST("/outbox/:id/retry", outboxHandler.RetryEvent)
+			admin.GET("/emails", notificationHandler.ListEmails)
+			admin.GET("/emails/:id", notificationHandler.GetEmail)
+			admin.POST("/emails/:id/retry", notificationHandler.RetryEmail)
+			admin.GET("/api-keys", authHandler.ListAPIKeys)
+			admin.POST("/api-keys", authHandler.CreateAPIKey)
+			admin.DELETE("/api-keys/:id", authHandler.RevokeAPIKey)
+			admin.POST("/tokens", authHandler.IssueToken)
 		}
 	}
 
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+type ChainConfig struct {
+	Key            string
+	ChainID        int64
+	Name           string
+	RPCURL         string
+	RPCURLs        []string
+	WSURL          string
+	Confirmations  int
+	NativeSymbol   string
+	DepositAddress string
+	NetworkFeeIDR  int64
+	Contracts      map[string]TokenContract
+}
+
+type TokenContract struct {
+	Address  string
+	Decimals int
+}
+
+type chainDefaults struct {
+	envPrefix     string
+	chainID       int64
+	name          string
+	rpcURL        string
+	fallbackURLs  []string
+	wsURL         string
+	confirmations int
+	nativeSymbol  string
+	contracts     map[string]TokenContract
+}
+
+// knownChains holds the defaults for chains that work out of the box. Any of
+// them, and any additional chain listed in CHAINS, can be configured through
+// <PREFIX>_CHAIN_ID, <PREFIX>_RPC_URL, <PREFIX>_RPC_URLS, <PREFIX>_WSS_URL,
+// <PREFIX>_CONFIRMATIONS, <PREFIX>_NATIVE_SYMBOL, <PREFIX>_DEPOSIT_ADDRESS,
+// <PREFIX>_NETWORK_FEE_IDR and <PREFIX>_<TOKEN>_CONTRACT / _DECIMALS.
+func knownChains() map[string]chainDefaults {
+	return map[string]chainDefaults{
+		"bsc-testnet": {
+			envPrefix: "BSC",
+			chainID:   97,
+			name:      "BSC Testnet",
+			rpcURL:    "https://data-seed-prebsc-1-s1.binance.org:8545",
+			fallbackURLs: []string{
+				"https://data-seed-prebsc-2-s1.binance.org:8545",
+				"https://bsc-testnet-rpc.publicnode.com",
+			},
+			wsURL:         "wss://bsc-testnet.drpc.org",
+			confirmations: 3,
+			nativeSymbol:  "BNB",
+			contracts: map[string]TokenContract{
+				"USDT": {Address: "0xCD60747D9Bbb1da2AfB2F834391f0FF6ccb15f1a", Decimals: 6},
+				"BUSD": {Address: "0xeD24FC36d5Ee211Ea25A80239Fb8C4Cfd80f12Ee", Decimals: 18},
+				"USDC": {Decimals: 18},
+			},
+		},
+		"polygon": {
+			envPrefix:     "POLYGON",
+			chainID:       137,
+			name:          "Polygon",
+			rpcURL:        "https://polygon-rpc.com",
+			fallbackURLs:  []string{"https://polygon-bor-rpc.publicnode.com"},
+			confirmations: 64,
+			nativeSymbol:  "POL",
+			contracts: map[string]TokenContract{
+				"USDT": {Address: "0xc2132D05D31c914a87C6611C10748AEb04B58e8F", Decimals: 6},
+				"USDC": {Address: "0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359", Decimals: 6},
+			},
+		},
+		"ethereum": {
+			envPrefix:     "ETHEREUM",
+			chainID:       1,
+			name:          "Ethereum",
+			rpcURL:        "https://ethereum-rpc.publicnode.com",
+			fallbackURLs:  []string{"https://eth.drpc.org"},
+			confirmations: 12,
+			nativeSymbol:  "ETH",
+			contracts: map[string]TokenContract{
+				"USDT": {Address: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Decimals: 6},
+				"USDC": {Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Decimals: 6},
+			},
+		},
+		"local": {
+			envPrefix:     "LOCAL",
+			chainID:       31337,
+			name:          "Local Dev Chain",
+			rpcURL:        "http://127.0.0.1:8545",
+			confirmations: 1,
+			nativeSymbol:  "ETH",
+			contracts: map[string]TokenContract{
+				"USDT": {Decimals: 6},
+				"USDC": {Decimals: 6},
+			},
+		},
+	}
+}
+
+func loadChains(cfg *Config, keys []string) []ChainConfig {
+	known := knownChains()
+
+	// The BSC Testnet defaults keep honouring the original single-chain
+	// variables (BSC_RPC_URL, USDT_CONTRACT, ...).
+	bsc := known["bsc-testnet"]
+	bsc.rpcURL = cfg.BSCRPCUrl
+	bsc.wsURL = cfg.BSCWebSocketURL
+	bsc.contracts["USDT"] = TokenContract{Address: cfg.USDTContract, Decimals: cfg.USDTDecimals}
+	for _, symbol := range []string{"USDC", "BUSD"} {
+		contract := bsc.contracts[symbol]
+		contract.Address = getEnv(symbol+"_CONTRACT", contract.Address)
+		contract.Decimals = getEnvInt(symbol+"_DECIMALS", contract.Decimals)
+		bsc.contracts[symbol] = contract
+	}
+	known["bsc-testnet"] = bsc
+
+	var chains []ChainConfig
+	for _, raw := range keys {
+		key := strings.ToLower(strings.TrimSpace(raw))
+		if key == "" {
+			continue
+		}
+
+		defaults, exists := known[key]
+		if !exists {
+			defaults = chainDefaults{
+				envPrefix:     strings.ToUpper(strings.ReplaceAll(key, "-", "_")),
+				name:          key,
+				confirmations: 1,
+				contracts:     map[string]TokenContract{},
+			}
+		}
+		prefix := defaults.envPrefix
+
+		chainID, _ := strconv.ParseInt(getEnv(prefix+"_CHAIN_ID", strconv.FormatInt(defaults.chainID, 10)), 10, 64)
+		chain := ChainConfig{
+			Key:            key,
+			ChainID:        chainID,
+			Name:           getEnv(prefix+"_NAME", defaults.name),
+			RPCURL:         getEnv(prefix+"_RPC_URL", defaults.rpcURL),
+			WSURL:          getEnv(prefix+"_WSS_URL", defaults.wsURL),
+			Confirmations:  getEnvInt(prefix+"_CONFIRMATIONS", defaults.confirmations),
+			NativeSymbol:   strings.ToUpper(getEnv(prefix+"_NATIVE_SYMBOL", defaults.nativeSymbol)),
+			DepositAddress: getEnv(prefix+"_DEPOSIT_ADDRESS", getEnv("DEPOSIT_ADDRESS", "")),
+			NetworkFeeIDR:  int64(getEnvInt(prefix+"_NETWORK_FEE_IDR", 0)),
+			Contracts:      make(map[string]TokenContract),
+		}
+
+		for _, symbol := range []string{"USDT", "USDC", "BUSD"} {
+			contract := defaults.contracts[symbol]
+			contract.Address = getEnv(prefix+"_"+symbol+"_CONTRACT", contract.Address)
+			contract.Decimals = getEnvInt(prefix+"_"+symbol+"_DECIMALS", contract.Decimals)
+			if contract.Address != "" {
+				chain.Contracts[symbol] = contract
+			}
+		}
+
+		// The primary RPC URL is always tried first; <PREFIX>_RPC_URLS lists
+		// the fallbacks, replacing the built-in ones.
+		chain.RPCURLs = appendUnique(nil, chain.RPCURL)
+		fallbacks := defaults.fallbackURLs
+		if raw := getEnv(prefix+"_RPC_URLS", ""); raw != "" {
+			fallbacks = strings.Split(raw, ",")
+		}
+		for _, url := range fallbacks {
+			chain.RPCURLs = appendUnique(chain.RPCURLs, strings.TrimSpace(url))
+		}
+
+		chains = append(chains, chain)
+	}
+
+	return chains
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
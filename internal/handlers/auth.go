@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/middleware"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuthHandler struct {
+	authService *services.AuthService
+}
+
+func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+	return &AuthHandler{authService: authService}
+}
+
+// Me returns the caller as the server sees it, so clients can check which
+// role and scope their credentials carry.
+func (ah *AuthHandler) Me(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, "Principal retrieved successfully", middleware.CurrentPrincipal(c))
+}
+
+func (ah *AuthHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := ah.authService.ListAPIKeys()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch API keys", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "API keys retrieved successfully", keys)
+}
+
+func (ah *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req services.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	key, err := ah.authService.CreateAPIKey(&req, c.GetString(middleware.AdminActorKey))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create API key", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "API key created successfully", key)
+}
+
+func (ah *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid API key ID", err.Error())
+		return
+	}
+
+	key, err := ah.authService.RevokeAPIKey(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "API key not found", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke API key", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "API key revoked successfully", key)
+}
+
+func (ah *AuthHandler) IssueToken(c *gin.Context) {
+	var req services.IssueTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	token, err := ah.authService.IssueToken(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrTokensDisabled) {
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "Token issuing is disabled", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to issue token", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Token issued successfully", token)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChainHandler struct {
+	chains            *services.ChainRegistry
+	rateService       *services.RateService
+	blockchainService *services.BlockchainService
+}
+
+func NewChainHandler(chains *services.ChainRegistry, rateService *services.RateService, blockchainService *services.BlockchainService) *ChainHandler {
+	return &ChainHandler{
+		chains:            chains,
+		rateService:       rateService,
+		blockchainService: blockchainService,
+	}
+}
+
+func (ch *ChainHandler) GetChains(c *gin.Context) {
+	chains := make([]gin.H, 0)
+	for _, chain := range ch.chains.Chains() {
+		chains = append(chains, gin.H{
+			"chain_id":      chain.ID,
+			"key":           chain.Key,
+			"name":          chain.Name,
+			"confirmations": chain.Confirmations,
+			"native_symbol": chain.NativeSymbol,
+			"default":       chain.ID == ch.chains.Default().ID,
+			"tokens":        ch.tokenEntries(chain),
+		})
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Chains retrieved successfully", chains)
+}
+
+func (ch *ChainHandler) GetTokens(c *gin.Context) {
+	tokens := make([]gin.H, 0)
+	for _, chain := range ch.chains.Chains() {
+		tokens = append(tokens, ch.tokenEntries(chain)...)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Payment tokens retrieved successfully", tokens)
+}
+
+func (ch *ChainHandler) GetRPCStatus(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, "RPC status retrieved successfully", ch.blockchainService.RPCStatus())
+}
+
+func (ch *ChainHandler) tokenEntries(chain *services.Chain) []gin.H {
+	defaultChain := ch.chains.Default()
+
+	entries := make([]gin.H, 0)
+	for _, token := range chain.Tokens() {
+		entry := gin.H{
+			"symbol":      token.Symbol,
+			"name":        token.Name,
+			"chain_id":    token.ChainID,
+			"contract":    token.Contract,
+			"decimals":    token.Decimals,
+			"native":      token.IsNative(),
+			"rate_source": token.RateSource,
+			"default":     chain.ID == defaultChain.ID && token.Symbol == ch.chains.DefaultToken(),
+		}
+
+		if rate, err := ch.rateService.GetTokenRate(token); err == nil {
+			entry["idr_rate"] = rate.IDRPerToken
+			entry["usdt_rate"] = rate.USDTPerToken
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"sermorpheus-engine-test/internal/middleware"
 	"sermorpheus-engine-test/internal/services"
 	"sermorpheus-engine-test/internal/utils"
 
@@ -31,5 +32,10 @@ func (ch *CustomerHandler) GetCustomer(c *gin.Context) {
 		return
 	}
 
+	if !middleware.CurrentPrincipal(c).CanAccessCustomer(customer.Email) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Customer not found", "record not found")
+		return
+	}
+
 	utils.SuccessResponse(c, http.StatusOK, "Customer retrieved successfully", customer)
 }
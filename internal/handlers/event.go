@@ -1,14 +1,20 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"sermorpheus-engine-test/internal/middleware"
 	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
 	"sermorpheus-engine-test/internal/services"
 	"sermorpheus-engine-test/internal/utils"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type EventHandler struct {
@@ -20,12 +26,58 @@ func NewEventHandler(eventService *services.EventService) *EventHandler {
 }
 
 type CreateEventRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Location    string  `json:"location" binding:"required"`
-	Schedule    string  `json:"schedule" binding:"required"`
-	PriceIDR    float64 `json:"price_idr" binding:"required,gt=0"`
-	Quota       int     `json:"quota" binding:"required,gt=0"`
+	Name        string    `json:"name" binding:"required"`
+	Description string    `json:"description"`
+	Location    string    `json:"location" binding:"required"`
+	Organizer   string    `json:"organizer"`
+	Schedule    string    `json:"schedule" binding:"required"`
+	PriceIDR    money.IDR `json:"price_idr" binding:"required,gt=0"`
+	Quota       int       `json:"quota" binding:"required,gt=0"`
+
+	SaleStartAt              *string `json:"sale_start_at"`
+	SaleEndAt                *string `json:"sale_end_at"`
+	MaxTicketsPerTransaction int     `json:"max_tickets_per_transaction" binding:"gte=0"`
+	MaxTicketsPerCustomer    int     `json:"max_tickets_per_customer" binding:"gte=0"`
+}
+
+type UpdateEventRequest struct {
+	Name        *string    `json:"name"`
+	Description *string    `json:"description"`
+	Location    *string    `json:"location"`
+	Organizer   *string    `json:"organizer"`
+	Schedule    *string    `json:"schedule"`
+	PriceIDR    *money.IDR `json:"price_idr" binding:"omitempty,gt=0"`
+	Quota       *int       `json:"quota" binding:"omitempty,gt=0"`
+
+	SaleStartAt              nullableString `json:"sale_start_at"`
+	SaleEndAt                nullableString `json:"sale_end_at"`
+	MaxTicketsPerTransaction *int           `json:"max_tickets_per_transaction" binding:"omitempty,gte=0"`
+	MaxTicketsPerCustomer    *int           `json:"max_tickets_per_customer" binding:"omitempty,gte=0"`
+}
+
+// nullableString is a JSON field that tells an absent value from an explicit
+// null, so an update can clear it.
+type nullableString struct {
+	Set   bool
+	Value *string
+}
+
+func (n *nullableString) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+	return json.Unmarshal(data, &n.Value)
+}
+
+// cleared reports whether the field was sent as null or an empty string.
+func (n nullableString) cleared() bool {
+	return n.Set && (n.Value == nil || *n.Value == "")
+}
+
+type CancelEventRequest struct {
+	Reason string `json:"reason" binding:"required"`
 }
 
 func (eh *EventHandler) CreateEvent(c *gin.Context) {
@@ -35,22 +87,52 @@ func (eh *EventHandler) CreateEvent(c *gin.Context) {
 		return
 	}
 
+	// Organizers create events under their own name.
+	if principal := middleware.CurrentPrincipal(c); principal.Role == services.RoleOrganizer {
+		if req.Organizer != "" && req.Organizer != principal.Organizer {
+			utils.ErrorResponseWithCode(c, http.StatusForbidden, "Forbidden", "FORBIDDEN", "Organizers can only create their own events")
+			return
+		}
+		req.Organizer = principal.Organizer
+	}
+
 	schedule, err := utils.ParseTimeISO(req.Schedule)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid schedule format", "Use ISO 8601 format")
 		return
 	}
 
+	saleStartAt, err := parseOptionalTime(req.SaleStartAt)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid sale_start_at format", "Use ISO 8601 format")
+		return
+	}
+
+	saleEndAt, err := parseOptionalTime(req.SaleEndAt)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid sale_end_at format", "Use ISO 8601 format")
+		return
+	}
+
 	event := &models.Event{
-		Name:        req.Name,
-		Description: req.Description,
-		Location:    req.Location,
-		Schedule:    *schedule,
-		PriceIDR:    req.PriceIDR,
-		Quota:       req.Quota,
+		Name:                     req.Name,
+		Description:              req.Description,
+		Location:                 req.Location,
+		Organizer:                req.Organizer,
+		Schedule:                 *schedule,
+		PriceIDR:                 req.PriceIDR,
+		Quota:                    req.Quota,
+		SaleStartAt:              saleStartAt,
+		SaleEndAt:                saleEndAt,
+		MaxTicketsPerTransaction: req.MaxTicketsPerTransaction,
+		MaxTicketsPerCustomer:    req.MaxTicketsPerCustomer,
 	}
 
 	if err := eh.eventService.CreateEvent(event); err != nil {
+		if isRequestError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create event", err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create event", err.Error())
 		return
 	}
@@ -64,25 +146,77 @@ func (eh *EventHandler) GetEvents(c *gin.Context) {
 	offsetStr := c.DefaultQuery("offset", "0")
 
 	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
+	if err != nil || limit <= 0 {
 		limit = 10
 	}
+	if limit > 100 {
+		limit = 100
+	}
 
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil {
 		offset = 0
 	}
 
-	events, err := eh.eventService.GetEvents(limit, offset)
+	filter := &services.EventFilter{
+		Query:    c.Query("q"),
+		Location: c.Query("location"),
+		Sort:     c.DefaultQuery("sort", "-created_at"),
+		Limit:    limit,
+		Offset:   offset,
+		Cursor:   c.Query("cursor"),
+	}
+
+	if filter.From, err = parseOptionalTime(queryPtr(c, "from")); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid from format", "Use ISO 8601 format")
+		return
+	}
+	if filter.To, err = parseOptionalTime(queryPtr(c, "to")); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid to format", "Use ISO 8601 format")
+		return
+	}
+
+	if value := c.Query("min_price"); value != "" {
+		minPrice, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid min_price", err.Error())
+			return
+		}
+		filter.MinPrice = (*money.IDR)(&minPrice)
+	}
+	if value := c.Query("max_price"); value != "" {
+		maxPrice, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid max_price", err.Error())
+			return
+		}
+		filter.MaxPrice = (*money.IDR)(&maxPrice)
+	}
+	if value := c.Query("available"); value != "" {
+		available, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid available flag", err.Error())
+			return
+		}
+		filter.Available = &available
+	}
+
+	page, err := eh.eventService.GetEvents(filter)
 	if err != nil {
+		if isRequestError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid event query", err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch events", err.Error())
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Events retrieved successfully", gin.H{
-		"events": events,
-		"limit":  limit,
-		"offset": offset,
+		"events":      page.Events,
+		"limit":       limit,
+		"offset":      offset,
+		"total":       page.Total,
+		"next_cursor": page.NextCursor,
 	})
 }
 
@@ -102,3 +236,151 @@ func (eh *EventHandler) GetEventByID(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Event retrieved successfully", event)
 }
+
+func (eh *EventHandler) UpdateEvent(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid event ID", err.Error())
+		return
+	}
+
+	var req UpdateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	if principal := middleware.CurrentPrincipal(c); principal.Role == services.RoleOrganizer &&
+		req.Organizer != nil && *req.Organizer != principal.Organizer {
+		utils.ErrorResponseWithCode(c, http.StatusForbidden, "Forbidden", "FORBIDDEN", "Organizers cannot hand events to another organizer")
+		return
+	}
+
+	updateReq := &services.UpdateEventRequest{
+		Name:        req.Name,
+		Description: req.Description,
+		Location:    req.Location,
+		Organizer:   req.Organizer,
+		PriceIDR:    req.PriceIDR,
+		Quota:       req.Quota,
+
+		MaxTicketsPerTransaction: req.MaxTicketsPerTransaction,
+		MaxTicketsPerCustomer:    req.MaxTicketsPerCustomer,
+	}
+
+	updateReq.ClearSaleStartAt = req.SaleStartAt.cleared()
+	if updateReq.SaleStartAt, err = parseOptionalTime(req.SaleStartAt.Value); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid sale_start_at format", "Use ISO 8601 format")
+		return
+	}
+
+	updateReq.ClearSaleEndAt = req.SaleEndAt.cleared()
+	if updateReq.SaleEndAt, err = parseOptionalTime(req.SaleEndAt.Value); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid sale_end_at format", "Use ISO 8601 format")
+		return
+	}
+
+	if req.Schedule != nil {
+		schedule, err := utils.ParseTimeISO(*req.Schedule)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid schedule format", "Use ISO 8601 format")
+			return
+		}
+		updateReq.Schedule = schedule
+	}
+
+	event, err := eh.eventService.UpdateEvent(id, updateReq)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Event not found", err.Error())
+			return
+		}
+		if isRequestError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update event", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update event", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Event updated successfully", event)
+}
+
+func (eh *EventHandler) DeleteEvent(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid event ID", err.Error())
+		return
+	}
+
+	if err := eh.eventService.DeleteEvent(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Event not found", err.Error())
+			return
+		}
+		if isRequestError(err) {
+			utils.ErrorResponse(c, http.StatusConflict, "Failed to delete event", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete event", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Event deleted successfully", nil)
+}
+
+func (eh *EventHandler) CancelEvent(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid event ID", err.Error())
+		return
+	}
+
+	var req CancelEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	cancellation, err := eh.eventService.CancelEvent(id, req.Reason)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Event not found", err.Error())
+			return
+		}
+		if isRequestError(err) {
+			utils.ErrorResponse(c, http.StatusConflict, "Failed to cancel event", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to cancel event", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Event cancelled successfully", cancellation)
+}
+
+// isRequestError reports whether err was caused by the request rather than
+// the server: a broken booking rule or an invalid value.
+func isRequestError(err error) bool {
+	var bookingErr *services.BookingError
+	var validationErr *services.ValidationError
+	return errors.As(err, &bookingErr) || errors.As(err, &validationErr)
+}
+
+func parseOptionalTime(value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	return utils.ParseTimeISO(*value)
+}
+
+func queryPtr(c *gin.Context, key string) *string {
+	value, ok := c.GetQuery(key)
+	if !ok {
+		return nil
+	}
+	return &value
+}
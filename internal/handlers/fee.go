@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type FeeHandler struct {
+	feeService *services.FeeService
+}
+
+func NewFeeHandler(feeService *services.FeeService) *FeeHandler {
+	return &FeeHandler{feeService: feeService}
+}
+
+func (fh *FeeHandler) ListFeeRules(c *gin.Context) {
+	rules, err := fh.feeService.ListFeeRules()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch fee rules", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Fee rules retrieved successfully", rules)
+}
+
+func (fh *FeeHandler) CreateFeeRule(c *gin.Context) {
+	var req services.CreateFeeRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	rule, err := fh.feeService.CreateFeeRule(&req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create fee rule", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Fee rule created successfully", rule)
+}
+
+func (fh *FeeHandler) UpdateFeeRule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid fee rule ID", err.Error())
+		return
+	}
+
+	var req services.UpdateFeeRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	rule, err := fh.feeService.UpdateFeeRule(id, &req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Fee rule not found", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update fee rule", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Fee rule updated successfully", rule)
+}
+
+func (fh *FeeHandler) DisableFeeRule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid fee rule ID", err.Error())
+		return
+	}
+
+	if err := fh.feeService.DisableFeeRule(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Fee rule not found", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable fee rule", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Fee rule disabled successfully", nil)
+}
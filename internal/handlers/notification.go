@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+func (nh *NotificationHandler) ListEmails(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit", "limit must be between 1 and 500")
+		return
+	}
+
+	var transactionID *uuid.UUID
+	if value := c.Query("transaction_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid transaction ID", err.Error())
+			return
+		}
+		transactionID = &id
+	}
+
+	messages, err := nh.notificationService.ListMessages(c.Query("status"), c.Query("kind"), transactionID, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch emails", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Emails retrieved successfully", messages)
+}
+
+func (nh *NotificationHandler) GetEmail(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid email ID", err.Error())
+		return
+	}
+
+	message, err := nh.notificationService.GetMessage(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Email not found", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch email", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Email retrieved successfully", message)
+}
+
+func (nh *NotificationHandler) RetryEmail(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid email ID", err.Error())
+		return
+	}
+
+	message, err := nh.notificationService.Retry(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "Email not found", "")
+		case errors.Is(err, services.ErrEmailAlreadySent):
+			utils.ErrorResponse(c, http.StatusConflict, "Email cannot be retried", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retry email", err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Email queued for sending", message)
+}
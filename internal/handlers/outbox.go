@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OutboxHandler struct {
+	outboxService *services.OutboxService
+}
+
+func NewOutboxHandler(outboxService *services.OutboxService) *OutboxHandler {
+	return &OutboxHandler{outboxService: outboxService}
+}
+
+func (oh *OutboxHandler) ListEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit", "limit must be between 1 and 500")
+		return
+	}
+
+	events, err := oh.outboxService.ListEvents(c.Query("status"), limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch outbox events", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Outbox events retrieved successfully", events)
+}
+
+func (oh *OutboxHandler) RetryEvent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid event ID", err.Error())
+		return
+	}
+
+	event, err := oh.outboxService.RetryNow(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "Outbox event not found", "")
+		case errors.Is(err, services.ErrOutboxEventPublished):
+			utils.ErrorResponse(c, http.StatusConflict, "Outbox event cannot be retried", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retry outbox event", err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Outbox event queued for publishing", event)
+}
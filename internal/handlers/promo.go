@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/money"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PromoHandler struct {
+	promoService *services.PromoService
+}
+
+func NewPromoHandler(promoService *services.PromoService) *PromoHandler {
+	return &PromoHandler{promoService: promoService}
+}
+
+type CreatePromoCodeRequest struct {
+	Code           string     `json:"code" binding:"required"`
+	Description    string     `json:"description"`
+	DiscountType   string     `json:"discount_type" binding:"required"`
+	PercentBps     int64      `json:"percent_bps"`
+	AmountIDR      money.IDR  `json:"amount_idr"`
+	MaxDiscountIDR money.IDR  `json:"max_discount_idr"`
+	MinOrderIDR    money.IDR  `json:"min_order_idr"`
+	EventID        *uuid.UUID `json:"event_id"`
+	MaxRedemptions int        `json:"max_redemptions" binding:"gte=0"`
+	MaxPerCustomer int        `json:"max_per_customer" binding:"gte=0"`
+	StartsAt       *string    `json:"starts_at"`
+	EndsAt         *string    `json:"ends_at"`
+}
+
+type UpdatePromoCodeRequest struct {
+	Description    *string    `json:"description"`
+	PercentBps     *int64     `json:"percent_bps"`
+	AmountIDR      *money.IDR `json:"amount_idr"`
+	MaxDiscountIDR *money.IDR `json:"max_discount_idr"`
+	MinOrderIDR    *money.IDR `json:"min_order_idr"`
+	MaxRedemptions *int       `json:"max_redemptions" binding:"omitempty,gte=0"`
+	MaxPerCustomer *int       `json:"max_per_customer" binding:"omitempty,gte=0"`
+	StartsAt       *string    `json:"starts_at"`
+	EndsAt         *string    `json:"ends_at"`
+	Status         *string    `json:"status"`
+}
+
+func (ph *PromoHandler) ListPromoCodes(c *gin.Context) {
+	promos, err := ph.promoService.ListPromoCodes()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch promo codes", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promo codes retrieved successfully", promos)
+}
+
+func (ph *PromoHandler) CreatePromoCode(c *gin.Context) {
+	var req CreatePromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	createReq := &services.CreatePromoCodeRequest{
+		Code:           req.Code,
+		Description:    req.Description,
+		DiscountType:   req.DiscountType,
+		PercentBps:     req.PercentBps,
+		AmountIDR:      req.AmountIDR,
+		MaxDiscountIDR: req.MaxDiscountIDR,
+		MinOrderIDR:    req.MinOrderIDR,
+		EventID:        req.EventID,
+		MaxRedemptions: req.MaxRedemptions,
+		MaxPerCustomer: req.MaxPerCustomer,
+	}
+
+	var err error
+	if createReq.StartsAt, err = parseOptionalTime(req.StartsAt); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid starts_at format", "Use ISO 8601 format")
+		return
+	}
+	if createReq.EndsAt, err = parseOptionalTime(req.EndsAt); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid ends_at format", "Use ISO 8601 format")
+		return
+	}
+
+	promo, err := ph.promoService.CreatePromoCode(createReq)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create promo code", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Promo code created successfully", promo)
+}
+
+func (ph *PromoHandler) UpdatePromoCode(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid promo code ID", err.Error())
+		return
+	}
+
+	var req UpdatePromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	updateReq := &services.UpdatePromoCodeRequest{
+		Description:    req.Description,
+		PercentBps:     req.PercentBps,
+		AmountIDR:      req.AmountIDR,
+		MaxDiscountIDR: req.MaxDiscountIDR,
+		MinOrderIDR:    req.MinOrderIDR,
+		MaxRedemptions: req.MaxRedemptions,
+		MaxPerCustomer: req.MaxPerCustomer,
+		Status:         req.Status,
+	}
+
+	if updateReq.StartsAt, err = parseOptionalTime(req.StartsAt); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid starts_at format", "Use ISO 8601 format")
+		return
+	}
+	if updateReq.EndsAt, err = parseOptionalTime(req.EndsAt); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid ends_at format", "Use ISO 8601 format")
+		return
+	}
+
+	promo, err := ph.promoService.UpdatePromoCode(id, updateReq)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Promo code not found", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update promo code", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promo code updated successfully", promo)
+}
+
+func (ph *PromoHandler) DisablePromoCode(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid promo code ID", err.Error())
+		return
+	}
+
+	if err := ph.promoService.DisablePromoCode(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Promo code not found", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable promo code", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promo code disabled successfully", nil)
+}
+
+func (ph *PromoHandler) GetRedemptions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid promo code ID", err.Error())
+		return
+	}
+
+	redemptions, err := ph.promoService.GetPromoRedemptions(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch promo redemptions", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promo redemptions retrieved successfully", redemptions)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/money"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type QuoteHandler struct {
+	quoteService *services.QuoteService
+}
+
+func NewQuoteHandler(quoteService *services.QuoteService) *QuoteHandler {
+	return &QuoteHandler{quoteService: quoteService}
+}
+
+type CreateQuoteRequest struct {
+	EventID  uuid.UUID `json:"event_id" binding:"required"`
+	Quantity int       `json:"quantity" binding:"required,gt=0"`
+	ChainID  int64     `json:"chain_id"`
+	Token    string    `json:"token"`
+
+	PromoCode string `json:"promo_code"`
+}
+
+func (qh *QuoteHandler) CreateQuote(c *gin.Context) {
+	var req CreateQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	quote, err := qh.quoteService.CreateQuote(&services.CreateQuoteRequest{
+		EventID:  req.EventID,
+		Quantity: req.Quantity,
+		ChainID:  req.ChainID,
+		Token:    req.Token,
+
+		PromoCode: req.PromoCode,
+	})
+	if err != nil {
+		var bookingErr *services.BookingError
+		if errors.As(err, &bookingErr) {
+			utils.ErrorResponseWithCode(c, http.StatusBadRequest, "Failed to create quote", bookingErr.Code, bookingErr.Message)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create quote", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Quote created successfully", gin.H{
+		"quote_id":       quote.ID,
+		"quote":          quote,
+		"fee":            feeSummary(quote.BaseIDR, quote.FeeIDR, quote.NetworkFeeIDR, quote.ChargedIDR, quote.FeeRule),
+		"usdt_amount":    quote.USDTAmount.Format(money.USDTDecimals),
+		"chain_id":       quote.ChainID,
+		"payment_token":  quote.PaymentToken,
+		"payment_amount": quote.TokenAmount.Format(quote.TokenDecimals),
+		"payment_units":  quote.TokenAmount,
+		"expires_at":     quote.ExpiresAt,
+		"expires_in":     int(time.Until(quote.ExpiresAt).Seconds()),
+	})
+}
+
+func (qh *QuoteHandler) GetQuote(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid quote ID", err.Error())
+		return
+	}
+
+	quote, err := qh.quoteService.GetQuoteByID(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Quote not found", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Quote retrieved successfully", gin.H{
+		"quote":   quote,
+		"expired": !time.Now().Before(quote.ExpiresAt),
+	})
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,16 +23,76 @@ func NewRateHandler(rateService *services.RateService) *RateHandler {
 func (rh *RateHandler) GetCurrentRate(c *gin.Context) {
 	rate, err := rh.rateService.GetCurrentRate()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get current rate",
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrNoTrustworthyRate) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{
+			"error":  "Failed to get current rate",
+			"detail": err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"rate_id":          rate.ID,
 		"idr_to_usdt_rate": rate.IDRToUSDTRate,
+		"market_rate":      rate.MarketRate,
+		"usdt_usd_rate":    rate.USDTUSDRate,
+		"usd_idr_rate":     rate.USDIDRRate,
+		"spread_percent":   rate.SpreadPercent,
+		"providers":        rate.Providers,
 		"created_at":       rate.CreatedAt,
-		"source":           "live_api",
+		"source":           rate.Source,
+	}
+
+	if rate.Source == services.RateSourceManualOverride {
+		if override := rh.rateService.GetActiveOverride(); override != nil {
+			response["override"] = gin.H{
+				"id":         override.ID,
+				"reason":     override.Reason,
+				"created_by": override.CreatedBy,
+				"expires_at": override.ExpiresAt,
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (rh *RateHandler) GetRateHistory(c *gin.Context) {
+	interval := c.DefaultQuery("interval", "hour")
+
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		parsed, err := utils.ParseTimeISO(value)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid to format", "Use ISO 8601 format")
+			return
+		}
+		to = *parsed
+	}
+
+	from := to.Add(-24 * time.Hour)
+	if value := c.Query("from"); value != "" {
+		parsed, err := utils.ParseTimeISO(value)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid from format", "Use ISO 8601 format")
+			return
+		}
+		from = *parsed
+	}
+
+	buckets, err := rh.rateService.GetRateHistory(from, to, interval)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to fetch rate history", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Rate history retrieved successfully", gin.H{
+		"from":     from,
+		"to":       to,
+		"interval": interval,
+		"buckets":  buckets,
 	})
 }
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/middleware"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SetRateOverrideRequest struct {
+	Rate            float64 `json:"rate" binding:"required,gt=0"`
+	Reason          string  `json:"reason" binding:"required"`
+	ExpiresAt       string  `json:"expires_at"`
+	DurationMinutes int     `json:"duration_minutes"`
+}
+
+func (rh *RateHandler) SetOverride(c *gin.Context) {
+	var req SetRateOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	var expiresAt time.Time
+	switch {
+	case req.ExpiresAt != "":
+		parsed, err := utils.ParseTimeISO(req.ExpiresAt)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid expires_at format", "Use ISO 8601 format")
+			return
+		}
+		expiresAt = *parsed
+	case req.DurationMinutes > 0:
+		expiresAt = time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Missing expiry", "Provide expires_at or duration_minutes")
+		return
+	}
+
+	override, err := rh.rateService.SetOverride(&services.RateOverrideRequest{
+		Rate:      req.Rate,
+		Reason:    req.Reason,
+		ExpiresAt: expiresAt,
+		Actor:     c.GetString(middleware.AdminActorKey),
+		ClaimedBy: c.GetString(middleware.AdminClaimedByKey),
+		RemoteIP:  c.ClientIP(),
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to set rate override", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Rate override set successfully", override)
+}
+
+func (rh *RateHandler) GetOverride(c *gin.Context) {
+	override := rh.rateService.GetActiveOverride()
+	if override == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "No active rate override", "")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Rate override retrieved successfully", override)
+}
+
+func (rh *RateHandler) RevokeOverride(c *gin.Context) {
+	err := rh.rateService.RevokeOverride(c.GetString(middleware.AdminActorKey), c.GetString(middleware.AdminClaimedByKey), c.ClientIP())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "No active rate override", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke rate override", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Rate override revoked successfully", nil)
+}
+
+func (rh *RateHandler) GetAuditLog(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit", "limit must be between 1 and 500")
+		return
+	}
+
+	entries, err := rh.rateService.GetRateAuditLog(limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch rate audit log", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Rate audit log retrieved successfully", entries)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RefundHandler struct {
+	refundService *services.RefundService
+}
+
+func NewRefundHandler(refundService *services.RefundService) *RefundHandler {
+	return &RefundHandler{refundService: refundService}
+}
+
+func (rh *RefundHandler) ListRefunds(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit", "limit must be between 1 and 500")
+		return
+	}
+
+	refunds, err := rh.refundService.ListRefunds(c.Query("status"), limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch refunds", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Refunds retrieved successfully", refunds)
+}
+
+func (rh *RefundHandler) CompleteRefund(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid refund ID", err.Error())
+		return
+	}
+
+	var req services.CompleteRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	refund, err := rh.refundService.CompleteRefund(id, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "Refund not found", "")
+		case errors.Is(err, services.ErrInvalidTxHash):
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid transaction hash", err.Error())
+		case errors.Is(err, services.ErrRefundCompleted):
+			utils.ErrorResponse(c, http.StatusConflict, "Refund cannot be completed", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to complete refund", err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Refund completed successfully", refund)
+}
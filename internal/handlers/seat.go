@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SeatHandler struct {
+	seatService *services.SeatService
+}
+
+func NewSeatHandler(seatService *services.SeatService) *SeatHandler {
+	return &SeatHandler{seatService: seatService}
+}
+
+func (sh *SeatHandler) ConfigureLayout(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid event ID", err.Error())
+		return
+	}
+
+	var req services.SeatingLayout
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	seats, err := sh.seatService.ConfigureLayout(id, &req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Event not found", err.Error())
+			return
+		}
+		if isRequestError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Failed to configure seating layout", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to configure seating layout", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Seating layout configured successfully", gin.H{
+		"event_id": id,
+		"seats":    seats,
+		"total":    len(seats),
+	})
+}
+
+func (sh *SeatHandler) GetSeats(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid event ID", err.Error())
+		return
+	}
+
+	seats, err := sh.seatService.GetSeats(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch seats", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Seats retrieved successfully", gin.H{
+		"event_id": id,
+		"seats":    seats,
+	})
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"sermorpheus-engine-test/internal/middleware"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TicketHandler struct {
+	transactionService *services.TransactionService
+}
+
+func NewTicketHandler(transactionService *services.TransactionService) *TicketHandler {
+	return &TicketHandler{transactionService: transactionService}
+}
+
+// GetTicket looks a ticket up by the code printed on the e-ticket, for gate
+// staff at the entrance and for customers reading their own tickets.
+func (th *TicketHandler) GetTicket(c *gin.Context) {
+	ticket, err := th.transactionService.GetTicketByCode(c.Param("code"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Ticket not found", err.Error())
+		return
+	}
+
+	if !middleware.CurrentPrincipal(c).CanAccessBooking(ticket.Customer.Email, ticket.Event.Organizer) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Ticket not found", "record not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Ticket retrieved successfully", ticket)
+}
@@ -1,35 +1,54 @@
 package handlers
 
 import (
-	"log"
+	"errors"
+	"io"
 	"net/http"
+	"sermorpheus-engine-test/internal/middleware"
+	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
 	"sermorpheus-engine-test/internal/services"
 	"sermorpheus-engine-test/internal/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+const eventStreamKeepAlive = 15 * time.Second
+
 type TransactionHandler struct {
 	transactionService *services.TransactionService
 	customerService    *services.CustomerService
-	blockchainService  *services.BlockchainService
+	paymentChecks      *services.PaymentCheckService
+	paymentStatus      *services.PaymentStatusHub
+	checkWait          time.Duration
 }
 
-func NewTransactionHandler(transactionService *services.TransactionService, customerService *services.CustomerService, blockchainService *services.BlockchainService) *TransactionHandler {
+func NewTransactionHandler(transactionService *services.TransactionService, customerService *services.CustomerService, paymentChecks *services.PaymentCheckService, paymentStatus *services.PaymentStatusHub, checkWait time.Duration) *TransactionHandler {
 	return &TransactionHandler{
 		transactionService: transactionService,
 		customerService:    customerService,
-		blockchainService:  blockchainService,
+		paymentChecks:      paymentChecks,
+		paymentStatus:      paymentStatus,
+		checkWait:          checkWait,
 	}
 }
 
 type CreateTransactionRequest struct {
-	CustomerEmail string    `json:"customer_email" binding:"required,email"`
-	CustomerName  string    `json:"customer_name" binding:"required"`
-	CustomerPhone string    `json:"customer_phone"`
-	EventID       uuid.UUID `json:"event_id" binding:"required"`
-	Quantity      int       `json:"quantity" binding:"required,gt=0"`
+	CustomerEmail string      `json:"customer_email" binding:"required,email"`
+	CustomerName  string      `json:"customer_name" binding:"required"`
+	CustomerPhone string      `json:"customer_phone"`
+	Locale        string      `json:"locale"`
+	EventID       uuid.UUID   `json:"event_id" binding:"required"`
+	Quantity      int         `json:"quantity" binding:"required,gt=0"`
+	SeatIDs       []uuid.UUID `json:"seat_ids"`
+	ChainID       int64       `json:"chain_id"`
+	Token         string      `json:"token"`
+
+	WaitlistEntryID *uuid.UUID `json:"waitlist_entry_id"`
+	QuoteID         *uuid.UUID `json:"quote_id"`
+	PromoCode       string     `json:"promo_code"`
 }
 
 func (th *TransactionHandler) CreateTransaction(c *gin.Context) {
@@ -39,7 +58,12 @@ func (th *TransactionHandler) CreateTransaction(c *gin.Context) {
 		return
 	}
 
-	customer, err := th.customerService.GetOrCreateCustomer(req.CustomerEmail, req.CustomerName, req.CustomerPhone)
+	if principal := middleware.CurrentPrincipal(c); !principal.CanAccessCustomer(req.CustomerEmail) {
+		utils.ErrorResponseWithCode(c, http.StatusForbidden, "Forbidden", "FORBIDDEN", "Customers can only book with their own email")
+		return
+	}
+
+	customer, err := th.customerService.GetOrCreateCustomer(req.CustomerEmail, req.CustomerName, req.CustomerPhone, req.Locale)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process customer", err.Error())
 		return
@@ -49,19 +73,40 @@ func (th *TransactionHandler) CreateTransaction(c *gin.Context) {
 		CustomerID: customer.ID,
 		EventID:    req.EventID,
 		Quantity:   req.Quantity,
+		SeatIDs:    req.SeatIDs,
+		ChainID:    req.ChainID,
+		Token:      req.Token,
+
+		WaitlistEntryID: req.WaitlistEntryID,
+		QuoteID:         req.QuoteID,
+		PromoCode:       req.PromoCode,
 	}
 
 	transaction, err := th.transactionService.CreateTransaction(transactionReq)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create transaction", err.Error())
+		var bookingErr *services.BookingError
+		if errors.As(err, &bookingErr) {
+			utils.ErrorResponseWithCode(c, http.StatusBadRequest, "Failed to create transaction", bookingErr.Code, bookingErr.Message)
+			return
+		}
+		if isRequestError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create transaction", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create transaction", err.Error())
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusCreated, "Transaction created successfully", gin.H{
 		"transaction":      transaction,
 		"payment_address":  transaction.PaymentAddress,
-		"usdt_amount":      transaction.USDTAmount,
-		"payment_deadline": transaction.PaymentLockedAt.Add(30 * 60 * 1000000000),
+		"fee":              feeSummary(transaction.BaseIDR, transaction.FeeIDR, transaction.NetworkFeeIDR, transaction.ChargedIDR, transaction.FeeRule),
+		"usdt_amount":      transaction.USDTAmount.Format(money.USDTDecimals),
+		"chain_id":         transaction.ChainID,
+		"payment_token":    transaction.PaymentToken,
+		"payment_amount":   transaction.TokenAmount.Format(transaction.TokenDecimals),
+		"payment_units":    transaction.TokenAmount,
+		"payment_deadline": transaction.PaymentDeadline,
 	})
 }
 
@@ -73,9 +118,8 @@ func (th *TransactionHandler) GetTransaction(c *gin.Context) {
 		return
 	}
 
-	transaction, err := th.transactionService.GetTransactionByID(id)
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, "Transaction not found", err.Error())
+	transaction, ok := th.loadTransaction(c, id)
+	if !ok {
 		return
 	}
 
@@ -91,8 +135,7 @@ func (th *TransactionHandler) ConfirmPayment(c *gin.Context) {
 	}
 
 	var req struct {
-		TxHash string  `json:"tx_hash" binding:"required"`
-		Amount float64 `json:"amount,omitempty"`
+		TxHash string `json:"tx_hash" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
@@ -115,9 +158,8 @@ func (th *TransactionHandler) CheckPayment(c *gin.Context) {
 		return
 	}
 
-	transaction, err := th.transactionService.GetTransactionByID(id)
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, "Transaction not found", err.Error())
+	transaction, ok := th.loadTransaction(c, id)
+	if !ok {
 		return
 	}
 
@@ -128,17 +170,146 @@ func (th *TransactionHandler) CheckPayment(c *gin.Context) {
 		return
 	}
 
-	go func() {
-		found := th.blockchainService.CheckRecentTransfer(transaction.ID, transaction.USDTAmount, transaction.PaymentAddress)
-		if found {
-			log.Printf("Manual check found payment for transaction %s", transaction.ID)
-		} else {
-			log.Printf("Manual check: no payment found for transaction %s", transaction.ID)
+	check, err := th.paymentChecks.Start(transaction)
+	if err != nil {
+		var bookingErr *services.BookingError
+		if errors.As(err, &bookingErr) {
+			utils.ErrorResponseWithCode(c, http.StatusBadRequest, "Failed to start payment check", bookingErr.Code, bookingErr.Message)
+			return
 		}
-	}()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start payment check", err.Error())
+		return
+	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Payment check initiated", gin.H{
-		"transaction_id": transaction.ID,
-		"checking":       true,
+	check, err = th.paymentChecks.Wait(c.Request.Context(), check, th.checkWait)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch payment check", err.Error())
+		return
+	}
+
+	if check.Status == services.PaymentCheckRunning {
+		utils.SuccessResponse(c, http.StatusAccepted, "Payment check still running", check)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Payment check finished", check)
+}
+
+func (th *TransactionHandler) GetPaymentCheck(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid transaction ID", err.Error())
+		return
+	}
+	checkID, err := uuid.Parse(c.Param("check_id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid check ID", err.Error())
+		return
+	}
+
+	if _, ok := th.loadTransaction(c, id); !ok {
+		return
+	}
+
+	check, err := th.paymentChecks.Get(id, checkID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Payment check not found", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Payment check retrieved successfully", check)
+}
+
+// StreamEvents is a Server-Sent Events stream of the transaction's payment
+// status. It starts with a "status" event for the current state, followed by
+// transfer_detected, confirmations, paid, expired or cancelled events as they
+// happen on any replica. The stream ends after a final status.
+func (th *TransactionHandler) StreamEvents(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid transaction ID", err.Error())
+		return
+	}
+
+	updates, unsubscribe := th.paymentStatus.Subscribe(id)
+	defer unsubscribe()
+
+	transaction, ok := th.loadTransaction(c, id)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	current := services.CurrentPaymentStatus(transaction)
+	c.SSEvent(current.Type, current)
+	if current.Final() {
+		return
+	}
+	if progress, ok := th.paymentStatus.Progress(id); ok {
+		c.SSEvent(progress.Type, progress)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case update, ok := <-updates:
+			if !ok {
+				// Fell too far behind; the client reconnects and gets a
+				// fresh snapshot.
+				return false
+			}
+			if update.IsResync() {
+				transaction, err := th.transactionService.GetTransactionByID(id)
+				if err != nil {
+					return false
+				}
+				if transaction.Status == current.Status {
+					return true
+				}
+				update = services.CurrentPaymentStatus(transaction)
+			}
+			c.SSEvent(update.Type, update)
+			current.Status = update.Status
+			return !update.Final()
+		}
 	})
 }
+
+// loadTransaction fetches a transaction the caller may read. Transactions of
+// other customers or organizers are reported as not found.
+func (th *TransactionHandler) loadTransaction(c *gin.Context, id uuid.UUID) (*models.Transaction, bool) {
+	transaction, err := th.transactionService.GetTransactionByID(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Transaction not found", err.Error())
+		return nil, false
+	}
+
+	principal := middleware.CurrentPrincipal(c)
+	if !principal.CanAccessBooking(transaction.Customer.Email, transaction.Event.Organizer) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Transaction not found", "record not found")
+		return nil, false
+	}
+	return transaction, true
+}
+
+// feeSummary groups the IDR fee breakdown of a booking for API responses.
+func feeSummary(base, fee, networkFee, charged money.IDR, rule string) gin.H {
+	return gin.H{
+		"base_idr":        base,
+		"fee_idr":         fee,
+		"network_fee_idr": networkFee,
+		"charged_idr":     charged,
+		"rule":            rule,
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/middleware"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WaitlistHandler struct {
+	waitlistService *services.WaitlistService
+	customerService *services.CustomerService
+}
+
+func NewWaitlistHandler(waitlistService *services.WaitlistService, customerService *services.CustomerService) *WaitlistHandler {
+	return &WaitlistHandler{
+		waitlistService: waitlistService,
+		customerService: customerService,
+	}
+}
+
+type JoinWaitlistRequest struct {
+	CustomerEmail string `json:"customer_email" binding:"required,email"`
+	CustomerName  string `json:"customer_name" binding:"required"`
+	CustomerPhone string `json:"customer_phone"`
+	Quantity      int    `json:"quantity" binding:"required,gt=0"`
+}
+
+func (wh *WaitlistHandler) JoinWaitlist(c *gin.Context) {
+	idStr := c.Param("id")
+	eventID, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid event ID", err.Error())
+		return
+	}
+
+	var req JoinWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	if principal := middleware.CurrentPrincipal(c); !principal.CanAccessCustomer(req.CustomerEmail) {
+		utils.ErrorResponseWithCode(c, http.StatusForbidden, "Forbidden", "FORBIDDEN", "Customers can only join with their own email")
+		return
+	}
+
+	customer, err := wh.customerService.GetOrCreateCustomer(req.CustomerEmail, req.CustomerName, req.CustomerPhone, "")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process customer", err.Error())
+		return
+	}
+
+	entry, err := wh.waitlistService.Join(eventID, customer.ID, req.Quantity)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Event not found", err.Error())
+			return
+		}
+		var bookingErr *services.BookingError
+		if errors.As(err, &bookingErr) {
+			utils.ErrorResponseWithCode(c, http.StatusBadRequest, "Failed to join waitlist", bookingErr.Code, bookingErr.Message)
+			return
+		}
+		if isRequestError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Failed to join waitlist", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to join waitlist", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Joined waitlist successfully", entry)
+}
+
+func (wh *WaitlistHandler) GetEntry(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid waitlist entry ID", err.Error())
+		return
+	}
+
+	position, ok := wh.loadEntry(c, id)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Waitlist entry retrieved successfully", position)
+}
+
+func (wh *WaitlistHandler) LeaveWaitlist(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid waitlist entry ID", err.Error())
+		return
+	}
+
+	if _, ok := wh.loadEntry(c, id); !ok {
+		return
+	}
+
+	if err := wh.waitlistService.Leave(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Waitlist entry not found", err.Error())
+			return
+		}
+		if isRequestError(err) {
+			utils.ErrorResponse(c, http.StatusConflict, "Failed to leave waitlist", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to leave waitlist", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Left waitlist successfully", nil)
+}
+
+// loadEntry fetches a waitlist entry the caller may read. Entries of other
+// customers or organizers are reported as not found.
+func (wh *WaitlistHandler) loadEntry(c *gin.Context, id uuid.UUID) (*services.WaitlistPosition, bool) {
+	position, err := wh.waitlistService.GetEntry(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Waitlist entry not found", err.Error())
+		return nil, false
+	}
+
+	entry := position.Entry
+	if !middleware.CurrentPrincipal(c).CanAccessBooking(entry.Customer.Email, entry.Event.Organizer) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Waitlist entry not found", "record not found")
+		return nil, false
+	}
+	return position, true
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+func (wh *WebhookHandler) ListWebhooks(c *gin.Context) {
+	subscriptions, err := wh.webhookService.ListSubscriptions()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch webhooks", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhooks retrieved successfully", subscriptions)
+}
+
+func (wh *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req services.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	subscription, err := wh.webhookService.CreateSubscription(&req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create webhook", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Webhook created successfully", subscription)
+}
+
+func (wh *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook ID", err.Error())
+		return
+	}
+
+	var req services.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	subscription, err := wh.webhookService.UpdateSubscription(id, &req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Webhook not found", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update webhook", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook updated successfully", subscription)
+}
+
+func (wh *WebhookHandler) DisableWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook ID", err.Error())
+		return
+	}
+
+	if err := wh.webhookService.DisableSubscription(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Webhook not found", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable webhook", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook disabled successfully", nil)
+}
+
+func (wh *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook ID", err.Error())
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit", "limit must be between 1 and 500")
+		return
+	}
+
+	deliveries, err := wh.webhookService.ListDeliveries(id, c.Query("status"), limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch webhook deliveries", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook deliveries retrieved successfully", deliveries)
+}
+
+func (wh *WebhookHandler) GetDelivery(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid delivery ID", err.Error())
+		return
+	}
+
+	delivery, err := wh.webhookService.GetDelivery(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Webhook delivery not found", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook delivery retrieved successfully", delivery)
+}
+
+func (wh *WebhookHandler) Redeliver(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid delivery ID", err.Error())
+		return
+	}
+
+	delivery, err := wh.webhookService.Redeliver(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Webhook delivery not found", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to queue redelivery", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Webhook redelivery queued", delivery)
+}
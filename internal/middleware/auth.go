@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"sermorpheus-engine-test/internal/services"
+	"sermorpheus-engine-test/internal/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	PrincipalKey      = "principal"
+	AdminActorKey     = "admin_actor"
+	AdminClaimedByKey = "admin_claimed_by"
+)
+
+// Authenticate identifies the caller from an API key or a JWT and stores it
+// as the request's principal. Credentials are read from
+// "Authorization: Bearer", X-API-Key, or X-Admin-Key for the built-in admin
+// key. Requests without credentials continue anonymously; requests with
+// invalid ones are rejected.
+func Authenticate(auth *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var principal *services.Principal
+		var err error
+
+		switch {
+		case c.GetHeader("X-Admin-Key") != "":
+			principal, err = auth.AuthenticateAdminKey(c.GetHeader("X-Admin-Key"), c.GetHeader("X-Admin-User"))
+		case c.GetHeader("X-API-Key") != "":
+			principal, err = auth.AuthenticateAPIKey(c.GetHeader("X-API-Key"))
+		case c.GetHeader("Authorization") != "":
+			credential, ok := bearerCredential(c.GetHeader("Authorization"))
+			if !ok {
+				utils.ErrorResponseWithCode(c, http.StatusUnauthorized, "Unauthorized", "INVALID_CREDENTIALS", "Authorization header must use the Bearer scheme")
+				c.Abort()
+				return
+			}
+			principal, err = authenticateBearer(auth, credential)
+		default:
+			c.Next()
+			return
+		}
+
+		if !setPrincipal(c, principal, err) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// QueryToken authenticates from the access_token query parameter when no
+// header credentials were sent. It is only for routes browsers open with
+// EventSource, which cannot set headers.
+func QueryToken(auth *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("access_token")
+		if token == "" || CurrentPrincipal(c) != nil {
+			c.Next()
+			return
+		}
+
+		principal, err := authenticateBearer(auth, token)
+		if !setPrincipal(c, principal, err) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects anonymous requests with 401 and principals without one
+// of roles with 403. Admins pass every role check.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := CurrentPrincipal(c)
+		if principal == nil {
+			utils.ErrorResponseWithCode(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHENTICATED", "An API key or bearer token is required")
+			c.Abort()
+			return
+		}
+		if !principal.HasRole(roles...) {
+			utils.ErrorResponseWithCode(c, http.StatusForbidden, "Forbidden", "FORBIDDEN", "The "+principal.Role+" role cannot use this endpoint")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireEventOrganizer limits routes on the event in the :id parameter to
+// principals that may manage it, so organizers only change their own events.
+func RequireEventOrganizer(events *services.EventService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := CurrentPrincipal(c)
+		if principal == nil || principal.Role == services.RoleAdmin {
+			c.Next()
+			return
+		}
+
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid event ID", err.Error())
+			c.Abort()
+			return
+		}
+		event, err := events.GetEventByID(id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.ErrorResponse(c, http.StatusNotFound, "Event not found", err.Error())
+			} else {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch event", err.Error())
+			}
+			c.Abort()
+			return
+		}
+		if !principal.CanManageEvent(event.Organizer) {
+			utils.ErrorResponseWithCode(c, http.StatusForbidden, "Forbidden", "FORBIDDEN", "Event belongs to another organizer")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentPrincipal returns the authenticated caller, or nil for anonymous
+// requests.
+func CurrentPrincipal(c *gin.Context) *services.Principal {
+	value, ok := c.Get(PrincipalKey)
+	if !ok {
+		return nil
+	}
+	principal, _ := value.(*services.Principal)
+	return principal
+}
+
+func bearerCredential(header string) (string, bool) {
+	scheme, credential, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	credential = strings.TrimSpace(credential)
+	return credential, credential != ""
+}
+
+func authenticateBearer(auth *services.AuthService, credential string) (*services.Principal, error) {
+	if services.IsAPIKey(credential) {
+		return auth.AuthenticateAPIKey(credential)
+	}
+	return auth.AuthenticateToken(credential)
+}
+
+// setPrincipal stores an authenticated principal, or answers the request if
+// authentication failed and reports false.
+func setPrincipal(c *gin.Context, principal *services.Principal, err error) bool {
+	if err != nil {
+		var authErr *services.AuthError
+		if errors.As(err, &authErr) {
+			utils.ErrorResponseWithCode(c, http.StatusUnauthorized, "Unauthorized", authErr.Code, authErr.Message)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to authenticate", err.Error())
+		}
+		c.Abort()
+		return false
+	}
+
+	c.Set(PrincipalKey, principal)
+	c.Set(AdminActorKey, principal.Subject)
+	c.Set(AdminClaimedByKey, principal.ClaimedBy)
+	return true
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sermorpheus-engine-test/internal/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,19 +9,28 @@ import (
 )
 
 type Event struct {
-	ID             uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Name           string          `gorm:"not null" json:"name"`
-	Description    string          `json:"description"`
-	Location       string          `gorm:"not null" json:"location"`
-	Schedule       time.Time       `gorm:"not null" json:"schedule"`
-	PriceIDR       float64         `gorm:"not null" json:"price_idr"`
-	Quota          int             `gorm:"not null" json:"quota"`
-	AvailableQuota int             `gorm:"not null" json:"available_quota"`
-	CreatedAt      time.Time       `json:"created_at"`
-	UpdatedAt      time.Time       `json:"updated_at"`
-	DeletedAt      *gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
-	Transactions   []Transaction   `json:"transactions,omitempty"`
-	Tickets        []Ticket        `json:"tickets,omitempty"`
+	ID                       uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name                     string          `gorm:"not null" json:"name"`
+	Description              string          `json:"description"`
+	Location                 string          `gorm:"not null" json:"location"`
+	Schedule                 time.Time       `gorm:"not null" json:"schedule"`
+	PriceIDR                 money.IDR       `gorm:"not null" json:"price_idr"`
+	Organizer                string          `gorm:"index" json:"organizer,omitempty"`
+	Quota                    int             `gorm:"not null" json:"quota"`
+	AvailableQuota           int             `gorm:"not null" json:"available_quota"`
+	SaleStartAt              *time.Time      `json:"sale_start_at,omitempty"`
+	SaleEndAt                *time.Time      `json:"sale_end_at,omitempty"`
+	MaxTicketsPerTransaction int             `gorm:"default:0" json:"max_tickets_per_transaction"`
+	MaxTicketsPerCustomer    int             `gorm:"default:0" json:"max_tickets_per_customer"`
+	HasSeating               bool            `gorm:"default:false" json:"has_seating"`
+	Status                   string          `gorm:"default:'active'" json:"status"`
+	CancelReason             string          `json:"cancel_reason,omitempty"`
+	CancelledAt              *time.Time      `json:"cancelled_at,omitempty"`
+	CreatedAt                time.Time       `json:"created_at"`
+	UpdatedAt                time.Time       `json:"updated_at"`
+	DeletedAt                *gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	Transactions             []Transaction   `json:"transactions,omitempty"`
+	Tickets                  []Ticket        `json:"tickets,omitempty"`
 }
 
 type Customer struct {
@@ -28,6 +38,7 @@ type Customer struct {
 	Email        string        `gorm:"uniqueIndex;not null" json:"email"`
 	Name         string        `gorm:"not null" json:"name"`
 	Phone        string        `json:"phone"`
+	Locale       string        `json:"locale,omitempty"`
 	CreatedAt    time.Time     `json:"created_at"`
 	UpdatedAt    time.Time     `json:"updated_at"`
 	Transactions []Transaction `json:"transactions,omitempty"`
@@ -39,12 +50,31 @@ type Transaction struct {
 	CustomerID             uuid.UUID               `gorm:"type:uuid;not null" json:"customer_id"`
 	EventID                uuid.UUID               `gorm:"type:uuid;not null" json:"event_id"`
 	Quantity               int                     `gorm:"not null" json:"quantity"`
-	TotalIDR               float64                 `gorm:"not null" json:"total_idr"`
-	USDTRate               float64                 `gorm:"not null" json:"usdt_rate"`
-	USDTAmount             float64                 `gorm:"not null" json:"usdt_amount"`
+	TotalIDR               money.IDR               `gorm:"not null" json:"total_idr"`
+	PromoCodeID            *uuid.UUID              `gorm:"type:uuid;index" json:"promo_code_id,omitempty"`
+	PromoCode              string                  `json:"promo_code,omitempty"`
+	DiscountIDR            money.IDR               `json:"discount_idr"`
+	BaseIDR                money.IDR               `json:"base_idr"`
+	FeeIDR                 money.IDR               `json:"fee_idr"`
+	NetworkFeeIDR          money.IDR               `json:"network_fee_idr"`
+	ChargedIDR             money.IDR               `json:"charged_idr"`
+	FeeRuleID              *uuid.UUID              `gorm:"type:uuid" json:"fee_rule_id,omitempty"`
+	FeeRule                string                  `json:"fee_rule"`
+	USDTRate               money.Rate              `gorm:"not null" json:"usdt_rate"`
+	USDTAmount             money.Units             `gorm:"not null" json:"usdt_amount"`
+	ChainID                int64                   `gorm:"default:97;index" json:"chain_id"`
+	PaymentToken           string                  `gorm:"default:'USDT'" json:"payment_token"`
+	TokenRate              money.Rate              `json:"token_rate"`
+	TokenAmount            money.Units             `json:"token_amount"`
+	TokenDecimals          int                     `gorm:"default:6" json:"token_decimals"`
+	PaymentMode            string                  `gorm:"default:'address'" json:"payment_mode"`
+	AmountSuffix           money.Units             `json:"amount_suffix"`
 	PaymentAddress         string                  `json:"payment_address"`
+	QuoteID                *uuid.UUID              `gorm:"type:uuid" json:"quote_id,omitempty"`
 	Status                 string                  `gorm:"default:'pending'" json:"status"`
 	PaymentLockedAt        *time.Time              `json:"payment_locked_at"`
+	PaymentDeadline        *time.Time              `gorm:"index" json:"payment_deadline"`
+	PaymentRemindedAt      *time.Time              `json:"payment_reminded_at,omitempty"`
 	PaymentConfirmedAt     *time.Time              `json:"payment_confirmed_at"`
 	CreatedAt              time.Time               `json:"created_at"`
 	UpdatedAt              time.Time               `json:"updated_at"`
@@ -60,6 +90,8 @@ type Ticket struct {
 	EventID       uuid.UUID   `gorm:"type:uuid;not null" json:"event_id"`
 	CustomerID    uuid.UUID   `gorm:"type:uuid;not null" json:"customer_id"`
 	TicketCode    string      `gorm:"uniqueIndex;not null" json:"ticket_code"`
+	SeatID        *uuid.UUID  `gorm:"type:uuid" json:"seat_id,omitempty"`
+	Seat          string      `json:"seat,omitempty"`
 	Status        string      `gorm:"default:'active'" json:"status"`
 	CreatedAt     time.Time   `json:"created_at"`
 	UpdatedAt     time.Time   `json:"updated_at"`
@@ -80,19 +112,330 @@ type PaymentAddress struct {
 type USDTRate struct {
 	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	IDRToUSDTRate float64   `gorm:"not null" json:"idr_to_usdt_rate"`
+	MarketRate    float64   `json:"market_rate"`
+	USDTUSDRate   float64   `json:"usdt_usd_rate"`
+	USDIDRRate    float64   `json:"usd_idr_rate"`
+	SpreadPercent float64   `json:"spread_percent"`
+	Source        string    `json:"source"`
+	Providers     string    `json:"providers"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
 type BlockchainTransaction struct {
 	ID            uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	TransactionID uuid.UUID   `gorm:"type:uuid;not null" json:"transaction_id"`
+	ChainID       int64       `gorm:"default:97" json:"chain_id"`
 	TxHash        string      `gorm:"uniqueIndex" json:"tx_hash"`
 	FromAddress   string      `json:"from_address"`
 	ToAddress     string      `json:"to_address"`
-	Amount        float64     `json:"amount"`
+	Amount        money.Units `json:"amount"`
+	Token         string      `gorm:"default:'USDT'" json:"token"`
+	TokenDecimals int         `gorm:"default:6" json:"token_decimals"`
 	Confirmations int         `gorm:"default:0" json:"confirmations"`
 	Status        string      `gorm:"default:'pending'" json:"status"`
 	CreatedAt     time.Time   `json:"created_at"`
 	UpdatedAt     time.Time   `json:"updated_at"`
 	Transaction   Transaction `json:"transaction,omitempty"`
 }
+
+type Refund struct {
+	ID            uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TransactionID uuid.UUID   `gorm:"type:uuid;not null;index" json:"transaction_id"`
+	EventID       uuid.UUID   `gorm:"type:uuid;not null;index" json:"event_id"`
+	CustomerID    uuid.UUID   `gorm:"type:uuid;not null" json:"customer_id"`
+	USDTAmount    money.Units `gorm:"not null" json:"usdt_amount"`
+	ChainID       int64       `gorm:"default:97" json:"chain_id"`
+	Token         string      `gorm:"default:'USDT'" json:"token"`
+	TokenAmount   money.Units `json:"token_amount"`
+	TokenDecimals int         `gorm:"default:6" json:"token_decimals"`
+	ToAddress     string      `json:"to_address"`
+	Reason        string      `json:"reason"`
+	Status        string      `gorm:"default:'pending'" json:"status"`
+	TxHash        string      `json:"tx_hash,omitempty"`
+	RefundedAt    *time.Time  `json:"refunded_at,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	Transaction   Transaction `json:"transaction,omitempty"`
+}
+
+type Seat struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	EventID       uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_seats_position" json:"event_id"`
+	Section       string     `gorm:"not null;uniqueIndex:idx_seats_position" json:"section"`
+	Row           string     `gorm:"not null;uniqueIndex:idx_seats_position" json:"row"`
+	Number        int        `gorm:"not null;uniqueIndex:idx_seats_position" json:"number"`
+	Status        string     `gorm:"default:'available';index" json:"status"`
+	TransactionID *uuid.UUID `gorm:"type:uuid;index" json:"transaction_id,omitempty"`
+	HeldUntil     *time.Time `json:"held_until,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type WaitlistEntry struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	EventID        uuid.UUID  `gorm:"type:uuid;not null;index:idx_waitlist_event_status" json:"event_id"`
+	CustomerID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Quantity       int        `gorm:"not null" json:"quantity"`
+	Status         string     `gorm:"default:'waiting';index:idx_waitlist_event_status" json:"status"`
+	OfferedAt      *time.Time `json:"offered_at,omitempty"`
+	OfferExpiresAt *time.Time `json:"offer_expires_at,omitempty"`
+	TransactionID  *uuid.UUID `gorm:"type:uuid" json:"transaction_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Event          Event      `json:"event,omitempty"`
+	Customer       Customer   `json:"customer,omitempty"`
+}
+
+type Quote struct {
+	ID                 uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	EventID            uuid.UUID   `gorm:"type:uuid;not null;index" json:"event_id"`
+	Quantity           int         `gorm:"not null" json:"quantity"`
+	TotalIDR           money.IDR   `gorm:"not null" json:"total_idr"`
+	PromoCode          string      `json:"promo_code,omitempty"`
+	DiscountIDR        money.IDR   `json:"discount_idr"`
+	BaseIDR            money.IDR   `json:"base_idr"`
+	FeeIDR             money.IDR   `json:"fee_idr"`
+	NetworkFeeIDR      money.IDR   `json:"network_fee_idr"`
+	ChargedIDR         money.IDR   `json:"charged_idr"`
+	FeeRuleID          *uuid.UUID  `gorm:"type:uuid" json:"fee_rule_id,omitempty"`
+	FeeRule            string      `json:"fee_rule"`
+	RateID             uuid.UUID   `gorm:"type:uuid;not null" json:"rate_id"`
+	USDTRate           money.Rate  `gorm:"not null" json:"usdt_rate"`
+	USDTAmount         money.Units `gorm:"not null" json:"usdt_amount"`
+	ChainID            int64       `gorm:"default:97" json:"chain_id"`
+	PaymentToken       string      `gorm:"default:'USDT'" json:"payment_token"`
+	TokenRate          money.Rate  `json:"token_rate"`
+	TokenAmount        money.Units `json:"token_amount"`
+	TokenDecimals      int         `gorm:"default:6" json:"token_decimals"`
+	PlatformFeePercent float64     `json:"platform_fee_percent"`
+	ExpiresAt          time.Time   `gorm:"not null;index" json:"expires_at"`
+	UsedAt             *time.Time  `json:"used_at,omitempty"`
+	TransactionID      *uuid.UUID  `gorm:"type:uuid" json:"transaction_id,omitempty"`
+	CreatedAt          time.Time   `json:"created_at"`
+}
+
+type RateCandle struct {
+	BucketStart time.Time `gorm:"primaryKey" json:"bucket_start"`
+	Open        float64   `gorm:"not null" json:"open"`
+	High        float64   `gorm:"not null" json:"high"`
+	Low         float64   `gorm:"not null" json:"low"`
+	Close       float64   `gorm:"not null" json:"close"`
+	Samples     int       `gorm:"not null" json:"samples"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type RateOverride struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	RateID    uuid.UUID  `gorm:"type:uuid;not null" json:"rate_id"`
+	Rate      float64    `gorm:"not null" json:"rate"`
+	Reason    string     `gorm:"not null" json:"reason"`
+	Status    string     `gorm:"default:'active';index" json:"status"`
+	CreatedBy string     `gorm:"not null" json:"created_by"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy string     `json:"revoked_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type RateAuditLog struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Action     string     `gorm:"not null;index" json:"action"`
+	OverrideID *uuid.UUID `gorm:"type:uuid;index" json:"override_id,omitempty"`
+	Actor      string     `gorm:"not null" json:"actor"`
+	ClaimedBy  string     `json:"claimed_by,omitempty"`
+	RemoteIP   string     `json:"remote_ip,omitempty"`
+	Details    string     `gorm:"type:text" json:"details"`
+	CreatedAt  time.Time  `gorm:"index" json:"created_at"`
+}
+
+type FeeRule struct {
+	ID                    uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name                  string     `gorm:"not null" json:"name"`
+	Scope                 string     `gorm:"not null;index" json:"scope"`
+	EventID               *uuid.UUID `gorm:"type:uuid;index" json:"event_id,omitempty"`
+	Organizer             string     `gorm:"index" json:"organizer,omitempty"`
+	PercentBps            int64      `gorm:"not null" json:"percent_bps"`
+	FixedIDR              money.IDR  `gorm:"not null" json:"fixed_idr"`
+	MinIDR                money.IDR  `gorm:"not null" json:"min_idr"`
+	MaxIDR                money.IDR  `gorm:"not null" json:"max_idr"`
+	PassThroughNetworkFee bool       `gorm:"not null" json:"pass_through_network_fee"`
+	Status                string     `gorm:"default:'active';index" json:"status"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+type PromoCode struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Code           string     `gorm:"uniqueIndex;not null" json:"code"`
+	Description    string     `json:"description,omitempty"`
+	DiscountType   string     `gorm:"not null" json:"discount_type"`
+	PercentBps     int64      `gorm:"not null" json:"percent_bps"`
+	AmountIDR      money.IDR  `gorm:"not null" json:"amount_idr"`
+	MaxDiscountIDR money.IDR  `gorm:"not null" json:"max_discount_idr"`
+	MinOrderIDR    money.IDR  `gorm:"not null" json:"min_order_idr"`
+	EventID        *uuid.UUID `gorm:"type:uuid;index" json:"event_id,omitempty"`
+	MaxRedemptions int        `gorm:"not null" json:"max_redemptions"`
+	MaxPerCustomer int        `gorm:"not null" json:"max_per_customer"`
+	RedeemedCount  int        `gorm:"not null" json:"redeemed_count"`
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	Status         string     `gorm:"default:'active';index" json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type PromoRedemption struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	PromoCodeID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"promo_code_id"`
+	TransactionID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"transaction_id"`
+	CustomerID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"customer_id"`
+	DiscountIDR   money.IDR  `gorm:"not null" json:"discount_idr"`
+	Status        string     `gorm:"default:'redeemed';index" json:"status"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	URL         string    `gorm:"not null" json:"url"`
+	Description string    `json:"description,omitempty"`
+	Events      string    `gorm:"not null" json:"events"`
+	Secret      string    `gorm:"not null" json:"-"`
+	Status      string    `gorm:"default:'active';index" json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID        `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SubscriptionID uuid.UUID        `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	EventID        uuid.UUID        `gorm:"type:uuid;not null;index" json:"event_id"`
+	EventType      string           `gorm:"not null;index" json:"event_type"`
+	Payload        string           `gorm:"type:text;not null" json:"payload"`
+	Status         string           `gorm:"default:'pending';index" json:"status"`
+	Attempts       int              `gorm:"default:0" json:"attempts"`
+	NextAttemptAt  time.Time        `gorm:"index" json:"next_attempt_at"`
+	LastStatusCode int              `json:"last_status_code,omitempty"`
+	LastError      string           `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty"`
+	RedeliveryOf   *uuid.UUID       `gorm:"type:uuid" json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time        `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	AttemptLog     []WebhookAttempt `gorm:"foreignKey:DeliveryID" json:"attempt_log,omitempty"`
+}
+
+type WebhookAttempt struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DeliveryID   uuid.UUID `gorm:"type:uuid;not null;index" json:"delivery_id"`
+	Attempt      int       `gorm:"not null" json:"attempt"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	Error        string    `gorm:"type:text" json:"error,omitempty"`
+	ResponseBody string    `gorm:"type:text" json:"response_body,omitempty"`
+	DurationMs   int64     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OutboxEvent is an event written in the same database transaction as the
+// change it describes and published afterwards by the outbox relay.
+type OutboxEvent struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	AggregateType  string     `gorm:"not null" json:"aggregate_type"`
+	AggregateID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"aggregate_id"`
+	EventType      string     `gorm:"not null;index" json:"event_type"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"default:'pending';index" json:"status"`
+	PublishedSinks string     `json:"published_sinks,omitempty"`
+	Attempts       int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index" json:"next_attempt_at"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	PublishedAt    *time.Time `json:"published_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// PaymentCheck is one on-demand scan of recent blocks for a transaction's
+// payment.
+// ChainCursor is the last block a chain's payment watcher has scanned, so
+// scanning resumes there after a restart.
+type ChainCursor struct {
+	ChainID     int64     `gorm:"primaryKey;autoIncrement:false" json:"chain_id"`
+	LastScanned uint64    `gorm:"not null" json:"last_scanned"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type PaymentCheck struct {
+	ID             uuid.UUID               `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TransactionID  uuid.UUID               `gorm:"type:uuid;not null;index" json:"transaction_id"`
+	ChainID        int64                   `json:"chain_id"`
+	Status         string                  `gorm:"default:'running';index" json:"status"`
+	Result         string                  `json:"result,omitempty"`
+	FromBlock      uint64                  `json:"from_block"`
+	ToBlock        uint64                  `json:"to_block"`
+	ConfirmedBlock uint64                  `json:"confirmed_block"`
+	BlocksScanned  int                     `json:"blocks_scanned"`
+	Candidates     []PaymentCheckCandidate `gorm:"type:text;serializer:json" json:"candidates"`
+	TxHash         string                  `json:"tx_hash,omitempty"`
+	Error          string                  `gorm:"type:text" json:"error,omitempty"`
+	StartedAt      time.Time               `json:"started_at"`
+	FinishedAt     *time.Time              `json:"finished_at,omitempty"`
+}
+
+// PaymentCheckCandidate is a transfer to the payment address found by a
+// payment check, and whether it pays the transaction.
+type PaymentCheckCandidate struct {
+	TxHash        string `json:"tx_hash"`
+	BlockNumber   uint64 `json:"block_number"`
+	From          string `json:"from"`
+	Token         string `json:"token"`
+	Amount        string `json:"amount"`
+	Confirmations int    `json:"confirmations"`
+	Match         string `json:"match"`
+}
+
+// EmailMessage is one email to a customer, rendered when it was queued and
+// kept as the log of what was sent.
+type EmailMessage struct {
+	ID            uuid.UUID         `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DedupeKey     string            `gorm:"uniqueIndex;not null" json:"-"`
+	Kind          string            `gorm:"not null;index" json:"kind"`
+	TransactionID *uuid.UUID        `gorm:"type:uuid;index" json:"transaction_id,omitempty"`
+	CustomerID    *uuid.UUID        `gorm:"type:uuid;index" json:"customer_id,omitempty"`
+	ToAddress     string            `gorm:"not null" json:"to_address"`
+	ToName        string            `json:"to_name,omitempty"`
+	Locale        string            `json:"locale"`
+	Subject       string            `gorm:"not null" json:"subject"`
+	TextBody      string            `gorm:"type:text" json:"text_body"`
+	HTMLBody      string            `gorm:"type:text" json:"html_body"`
+	Attachments   []EmailAttachment `gorm:"type:text;serializer:json" json:"attachments,omitempty"`
+	Status        string            `gorm:"default:'pending';index" json:"status"`
+	Attempts      int               `gorm:"default:0" json:"attempts"`
+	NextAttemptAt time.Time         `gorm:"index" json:"next_attempt_at"`
+	LastError     string            `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt        *time.Time        `json:"sent_at,omitempty"`
+	CreatedAt     time.Time         `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+type EmailAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"`
+}
+
+// APIKey authenticates a server-to-server client. Only a hash of the key is
+// stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Role       string     `gorm:"not null" json:"role"`
+	Organizer  string     `gorm:"index" json:"organizer,omitempty"`
+	Prefix     string     `gorm:"not null" json:"prefix"`
+	KeyHash    string     `gorm:"uniqueIndex;not null" json:"-"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
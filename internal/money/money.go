@@ -0,0 +1,245 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// USDTDecimals is the precision of the USDT reference amounts stored next to
+// every booking, independent of the decimals of the token actually paid.
+const USDTDecimals = 6
+
+// IDR is an amount in whole rupiah.
+type IDR int64
+
+func (a IDR) GormDataType() string {
+	return "bigint"
+}
+
+// MulBasisPoints returns a share of the amount in basis points (1/100 of a
+// percent), rounded half up to the rupiah.
+func (a IDR) MulBasisPoints(bps int64) IDR {
+	product := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(bps))
+	return IDR(divRound(product, big.NewInt(10000)).Int64())
+}
+
+// BasisPoints converts a configured percentage such as 1.2 into basis points.
+func BasisPoints(percent float64) int64 {
+	return int64(math.Round(percent * 100))
+}
+
+// Units is a token amount in the token's smallest unit (10^-decimals of a
+// token). It is stored as numeric(78,0), which holds any uint256, and encoded
+// in JSON as a string so clients never parse it into a float.
+type Units struct {
+	value *big.Int
+}
+
+func NewUnits(value *big.Int) Units {
+	if value == nil {
+		return Units{}
+	}
+	return Units{value: new(big.Int).Set(value)}
+}
+
+func UnitsFromInt64(value int64) Units {
+	return Units{value: big.NewInt(value)}
+}
+
+// ParseUnits parses an integer amount of base units.
+func ParseUnits(s string) (Units, error) {
+	value, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+	if !ok {
+		return Units{}, fmt.Errorf("invalid base unit amount %q", s)
+	}
+	return Units{value: value}, nil
+}
+
+// ParseAmount parses a decimal token amount such as "25.5" into base units.
+// Amounts with more fractional digits than the token has are rejected rather
+// than rounded, as are negative and signed amounts.
+func ParseAmount(s string, decimals int) (Units, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		return Units{}, fmt.Errorf("amount %q is negative", s)
+	}
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole+fraction == "" || !isDigits(whole) || !isDigits(fraction) {
+		return Units{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(fraction) > decimals {
+		return Units{}, fmt.Errorf("amount %q has more than %d decimals", s, decimals)
+	}
+	units, err := ParseUnits(whole + fraction + strings.Repeat("0", decimals-len(fraction)))
+	if err != nil {
+		return Units{}, fmt.Errorf("invalid amount %q", s)
+	}
+	return units, nil
+}
+
+// ToUnits converts an IDR amount into base units of a token priced at rate
+// IDR per whole token, rounded half up.
+func ToUnits(amount IDR, rate Rate, decimals int) Units {
+	if rate <= 0 {
+		return Units{}
+	}
+	numerator := new(big.Int).Mul(big.NewInt(int64(amount)), pow10(decimals+RateDecimals))
+	return Units{value: divRound(numerator, big.NewInt(int64(rate)))}
+}
+
+// Int returns a copy of the amount as a big.Int.
+func (u Units) Int() *big.Int {
+	if u.value == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(u.value)
+}
+
+func (u Units) Sign() int {
+	if u.value == nil {
+		return 0
+	}
+	return u.value.Sign()
+}
+
+func (u Units) Cmp(other Units) int {
+	return u.Int().Cmp(other.Int())
+}
+
+func (u Units) Equal(other Units) bool {
+	return u.Cmp(other) == 0
+}
+
+func (u Units) Add(other Units) Units {
+	return Units{value: new(big.Int).Add(u.Int(), other.Int())}
+}
+
+// Round rounds an amount of a token with the given decimals to at most places
+// fractional digits, half up.
+func (u Units) Round(decimals, places int) Units {
+	if places >= decimals {
+		return u
+	}
+	step := pow10(decimals - places)
+	rounded := divRound(u.Int(), step)
+	return Units{value: rounded.Mul(rounded, step)}
+}
+
+// RoundUp rounds an amount of a token with the given decimals up to at most
+// places fractional digits.
+func (u Units) RoundUp(decimals, places int) Units {
+	if places >= decimals {
+		return u
+	}
+	step := pow10(decimals - places)
+	rounded := u.Int()
+	rounded.Add(rounded, step).Sub(rounded, big.NewInt(1)).Quo(rounded, step)
+	return Units{value: rounded.Mul(rounded, step)}
+}
+
+// String returns the amount in base units.
+func (u Units) String() string {
+	return u.Int().String()
+}
+
+// Format renders the amount in whole tokens, e.g. "25.5" for 25500000 base
+// units of a 6-decimal token.
+func (u Units) Format(decimals int) string {
+	value := u.Int()
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+		value.Neg(value)
+	}
+
+	digits := value.String()
+	if decimals <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	whole := digits[:len(digits)-decimals]
+	fraction := strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+func (Units) GormDataType() string {
+	return "numeric(78,0)"
+}
+
+func (u Units) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+func (u *Units) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*u = Units{}
+		return nil
+	case int64:
+		*u = Units{value: big.NewInt(v)}
+		return nil
+	case []byte:
+		return u.scanString(string(v))
+	case string:
+		return u.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into money.Units", src)
+	}
+}
+
+func (u *Units) scanString(s string) error {
+	// numeric(78,0) may still come back as "123.0" from some drivers.
+	whole, fraction, _ := strings.Cut(s, ".")
+	if strings.Trim(fraction, "0") != "" {
+		return fmt.Errorf("base unit amount %q is not an integer", s)
+	}
+	parsed, err := ParseUnits(whole)
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+func (u Units) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
+
+func (u *Units) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseUnits(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// divRound divides two non-negative integers, rounding half up.
+func divRound(numerator, denominator *big.Int) *big.Int {
+	doubled := new(big.Int).Lsh(numerator, 1)
+	doubled.Add(doubled, denominator)
+	return doubled.Quo(doubled, new(big.Int).Lsh(denominator, 1))
+}
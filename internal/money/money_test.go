@@ -0,0 +1,310 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in       string
+		decimals int
+		want     string
+		wantErr  bool
+	}{
+		{in: "25.5", decimals: 6, want: "25500000"},
+		{in: "25", decimals: 6, want: "25000000"},
+		{in: " 0.000001 ", decimals: 6, want: "1"},
+		{in: ".5", decimals: 6, want: "500000"},
+		{in: "5.", decimals: 6, want: "5000000"},
+		{in: "0", decimals: 0, want: "0"},
+		{in: "1.000000000000000001", decimals: 18, want: "1000000000000000001"},
+		{in: "1.0000001", decimals: 6, wantErr: true},
+		{in: "-5", decimals: 6, wantErr: true},
+		{in: "-0.5", decimals: 6, wantErr: true},
+		{in: "+5", decimals: 6, wantErr: true},
+		{in: "", decimals: 6, wantErr: true},
+		{in: ".", decimals: 6, wantErr: true},
+		{in: "1.2.3", decimals: 6, wantErr: true},
+		{in: "1e6", decimals: 6, wantErr: true},
+		{in: "1,5", decimals: 6, wantErr: true},
+		{in: "0x10", decimals: 6, wantErr: true},
+		{in: "1.-5", decimals: 6, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.in, tt.decimals)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseAmount(%q, %d) = %s, want error", tt.in, tt.decimals, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAmount(%q, %d) returned error: %v", tt.in, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseAmount(%q, %d) = %s, want %s", tt.in, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestToUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   IDR
+		rate     Rate
+		decimals int
+		want     string
+	}{
+		{name: "exact", amount: 16250, rate: 16250 * 1e8, decimals: 6, want: "1000000"},
+		{name: "rounds half up", amount: 1, rate: 2 * 1e8, decimals: 0, want: "1"},
+		{name: "rounds down below half", amount: 1, rate: 3 * 1e8, decimals: 0, want: "0"},
+		{name: "fractional rate", amount: 100000, rate: 16234_56789012, decimals: 6, want: "6159696"},
+		{name: "18 decimals", amount: 100000, rate: 16250 * 1e8, decimals: 18, want: "6153846153846153846"},
+		{name: "zero amount", amount: 0, rate: 16250 * 1e8, decimals: 6, want: "0"},
+		{name: "zero rate", amount: 100000, rate: 0, decimals: 6, want: "0"},
+		{name: "negative rate", amount: 100000, rate: -1, decimals: 6, want: "0"},
+	}
+
+	for _, tt := range tests {
+		if got := ToUnits(tt.amount, tt.rate, tt.decimals); got.String() != tt.want {
+			t.Errorf("%s: ToUnits(%d, %s, %d) = %s, want %s", tt.name, tt.amount, tt.rate, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestDivRound(t *testing.T) {
+	tests := []struct {
+		numerator, denominator int64
+		want                   int64
+	}{
+		{10, 5, 2},
+		{7, 2, 4},
+		{5, 2, 3},
+		{4, 3, 1},
+		{5, 3, 2},
+		{1, 3, 0},
+		{0, 7, 0},
+		{14999, 10000, 1},
+		{15000, 10000, 2},
+	}
+
+	for _, tt := range tests {
+		got := divRound(big.NewInt(tt.numerator), big.NewInt(tt.denominator))
+		if got.Int64() != tt.want {
+			t.Errorf("divRound(%d, %d) = %s, want %d", tt.numerator, tt.denominator, got, tt.want)
+		}
+	}
+}
+
+func TestMulBasisPoints(t *testing.T) {
+	tests := []struct {
+		amount IDR
+		bps    int64
+		want   IDR
+	}{
+		{amount: 100000, bps: 250, want: 2500},
+		{amount: 100000, bps: 0, want: 0},
+		{amount: 100000, bps: 10000, want: 100000},
+		{amount: 150, bps: 100, want: 2},
+		{amount: 149, bps: 100, want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.MulBasisPoints(tt.bps); got != tt.want {
+			t.Errorf("IDR(%d).MulBasisPoints(%d) = %d, want %d", tt.amount, tt.bps, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		units    Units
+		decimals int
+		want     string
+	}{
+		{units: UnitsFromInt64(25500000), decimals: 6, want: "25.5"},
+		{units: UnitsFromInt64(25000000), decimals: 6, want: "25"},
+		{units: UnitsFromInt64(1), decimals: 6, want: "0.000001"},
+		{units: UnitsFromInt64(0), decimals: 6, want: "0"},
+		{units: Units{}, decimals: 6, want: "0"},
+		{units: UnitsFromInt64(123), decimals: 0, want: "123"},
+		{units: UnitsFromInt64(-1500000), decimals: 6, want: "-1.5"},
+		{units: UnitsFromInt64(-1), decimals: 6, want: "-0.000001"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.units.Format(tt.decimals); got != tt.want {
+			t.Errorf("Units(%s).Format(%d) = %q, want %q", tt.units, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, in := range []string{"0", "1", "0.000001", "25.5", "123456789.123456"} {
+		units, err := ParseAmount(in, 6)
+		if err != nil {
+			t.Fatalf("ParseAmount(%q) returned error: %v", in, err)
+		}
+		if got := units.Format(6); got != in {
+			t.Errorf("Format(ParseAmount(%q)) = %q", in, got)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		units            int64
+		decimals, places int
+		want, wantUp     string
+	}{
+		{units: 1234567, decimals: 6, places: 2, want: "1230000", wantUp: "1240000"},
+		{units: 1235000, decimals: 6, places: 2, want: "1240000", wantUp: "1240000"},
+		{units: 1230000, decimals: 6, places: 2, want: "1230000", wantUp: "1230000"},
+		{units: 1234567, decimals: 6, places: 6, want: "1234567", wantUp: "1234567"},
+		{units: 1234567, decimals: 6, places: 8, want: "1234567", wantUp: "1234567"},
+	}
+
+	for _, tt := range tests {
+		units := UnitsFromInt64(tt.units)
+		if got := units.Round(tt.decimals, tt.places); got.String() != tt.want {
+			t.Errorf("Round(%d, %d, %d) = %s, want %s", tt.units, tt.decimals, tt.places, got, tt.want)
+		}
+		if got := units.RoundUp(tt.decimals, tt.places); got.String() != tt.wantUp {
+			t.Errorf("RoundUp(%d, %d, %d) = %s, want %s", tt.units, tt.decimals, tt.places, got, tt.wantUp)
+		}
+	}
+}
+
+func TestUnitsScan(t *testing.T) {
+	tests := []struct {
+		src     interface{}
+		want    string
+		wantErr bool
+	}{
+		{src: nil, want: "0"},
+		{src: int64(42), want: "42"},
+		{src: "115792089237316195423570985008687907853269984665640564039457584007913129639935", want: "115792089237316195423570985008687907853269984665640564039457584007913129639935"},
+		{src: []byte("6172772"), want: "6172772"},
+		{src: "6172772.000", want: "6172772"},
+		{src: "6172772.5", wantErr: true},
+		{src: "abc", wantErr: true},
+		{src: 1.5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var units Units
+		err := units.Scan(tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Scan(%v) = %s, want error", tt.src, units)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Scan(%v) returned error: %v", tt.src, err)
+			continue
+		}
+		if units.String() != tt.want {
+			t.Errorf("Scan(%v) = %s, want %s", tt.src, units, tt.want)
+		}
+	}
+}
+
+func TestUnitsJSON(t *testing.T) {
+	data, err := json.Marshal(UnitsFromInt64(6172772))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"6172772"` {
+		t.Errorf("Marshal = %s, want \"6172772\"", data)
+	}
+
+	var units Units
+	if err := json.Unmarshal([]byte(`"6172772"`), &units); err != nil {
+		t.Fatal(err)
+	}
+	if units.String() != "6172772" {
+		t.Errorf("Unmarshal = %s, want 6172772", units)
+	}
+	if err := json.Unmarshal([]byte(`"1.5"`), &units); err == nil {
+		t.Error("Unmarshal of a fractional amount succeeded, want error")
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		wantErr bool
+	}{
+		{in: "16250", want: 16250_00000000},
+		{in: "16250.12345678", want: 16250_12345678},
+		{in: "0.00000001", want: 1},
+		{in: "16250.123456789", wantErr: true},
+		{in: "-16250", wantErr: true},
+		{in: "99999999999", wantErr: true},
+		{in: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRate(%q) = %s, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRate(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRateScan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want Rate
+	}{
+		{src: nil, want: 0},
+		{src: int64(16250), want: 16250_00000000},
+		{src: 16250.5, want: 16250_50000000},
+		{src: "16250.12345678", want: 16250_12345678},
+		{src: []byte("16250.10000000"), want: 16250_10000000},
+	}
+
+	for _, tt := range tests {
+		var rate Rate
+		if err := rate.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v) returned error: %v", tt.src, err)
+			continue
+		}
+		if rate != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.src, rate, tt.want)
+		}
+	}
+}
+
+func TestRateString(t *testing.T) {
+	tests := []struct {
+		rate Rate
+		want string
+	}{
+		{rate: 16250_00000000, want: "16250"},
+		{rate: 16250_12345678, want: "16250.12345678"},
+		{rate: 16250_10000000, want: "16250.1"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rate.String(); got != tt.want {
+			t.Errorf("Rate(%d).String() = %q, want %q", int64(tt.rate), got, tt.want)
+		}
+	}
+}
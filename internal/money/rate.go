@@ -0,0 +1,92 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RateDecimals is the fixed precision of exchange rates.
+const RateDecimals = 8
+
+const rateScale = 1e8
+
+// Rate is an exchange rate in IDR per whole token, held as a fixed-point
+// decimal with RateDecimals fractional digits.
+type Rate int64
+
+// RateFromFloat converts a market rate, which providers report as a float,
+// into a fixed-point rate.
+func RateFromFloat(f float64) Rate {
+	return Rate(math.Round(f * rateScale))
+}
+
+func ParseRate(s string) (Rate, error) {
+	units, err := ParseAmount(s, RateDecimals)
+	if err != nil {
+		return 0, err
+	}
+	if !units.Int().IsInt64() {
+		return 0, fmt.Errorf("rate %q out of range", s)
+	}
+	return Rate(units.Int().Int64()), nil
+}
+
+func (r Rate) Float64() float64 {
+	return float64(r) / rateScale
+}
+
+func (r Rate) String() string {
+	return UnitsFromInt64(int64(r)).Format(RateDecimals)
+}
+
+func (Rate) GormDataType() string {
+	return "numeric(20,8)"
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r *Rate) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*r = 0
+		return nil
+	case int64:
+		*r = Rate(v * rateScale)
+		return nil
+	case float64:
+		*r = RateFromFloat(v)
+		return nil
+	case []byte:
+		return r.scanString(string(v))
+	case string:
+		return r.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into money.Rate", src)
+	}
+}
+
+func (r *Rate) scanString(s string) error {
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// MarshalJSON encodes the rate as an exact JSON number.
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	if _, err := strconv.ParseFloat(strings.Trim(string(data), `"`), 64); err != nil {
+		return fmt.Errorf("invalid rate %s", data)
+	}
+	return r.scanString(strings.Trim(string(data), `"`))
+}
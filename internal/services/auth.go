@@ -0,0 +1,374 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sermorpheus-engine-test/internal/models"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Roles. Admins may do everything; organizers manage their own events;
+// gate staff look up tickets at the entrance; customers book and read their
+// own transactions and tickets.
+const (
+	RoleAdmin     = "admin"
+	RoleOrganizer = "organizer"
+	RoleGateStaff = "gate_staff"
+	RoleCustomer  = "customer"
+)
+
+var Roles = []string{RoleAdmin, RoleOrganizer, RoleGateStaff, RoleCustomer}
+
+// Ways a principal authenticated.
+const (
+	AuthMethodAdminKey = "admin_key"
+	AuthMethodAPIKey   = "api_key"
+	AuthMethodJWT      = "jwt"
+)
+
+const (
+	apiKeyPrefix       = "sk_"
+	apiKeyUsageRefresh = time.Minute
+	minJWTSecretLength = 32
+	adminKeySubject    = "admin"
+)
+
+// ErrTokensDisabled is returned when a token is requested but JWT_SECRET is
+// not configured.
+var ErrTokensDisabled = errors.New("JWT_SECRET is not configured")
+
+// AuthError is returned for credentials that are malformed, unknown or no
+// longer valid.
+type AuthError struct {
+	Code    string
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Method    string     `json:"method"`
+	Subject   string     `json:"subject"`
+	Role      string     `json:"role"`
+	Email     string     `json:"email,omitempty"`
+	Organizer string     `json:"organizer,omitempty"`
+	APIKeyID  *uuid.UUID `json:"api_key_id,omitempty"`
+	// ClaimedBy is the operator named by the caller in X-Admin-User. It is
+	// not verified and is never used as the subject.
+	ClaimedBy string `json:"claimed_by,omitempty"`
+}
+
+// HasRole reports whether the principal has one of roles. Admins have every
+// role.
+func (p *Principal) HasRole(roles ...string) bool {
+	if p.Role == RoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if p.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CanManageEvent reports whether the principal may act on an event of the
+// given organizer. Organizers, and gate staff tied to an organizer, are
+// limited to that organizer's events.
+func (p *Principal) CanManageEvent(organizer string) bool {
+	switch p.Role {
+	case RoleAdmin:
+		return true
+	case RoleOrganizer:
+		return p.Organizer != "" && p.Organizer == organizer
+	case RoleGateStaff:
+		return p.Organizer == "" || p.Organizer == organizer
+	}
+	return false
+}
+
+// CanAccessCustomer reports whether the principal may read the data of the
+// customer with the given email.
+func (p *Principal) CanAccessCustomer(email string) bool {
+	if p.Role == RoleCustomer {
+		return p.Email != "" && strings.EqualFold(p.Email, email)
+	}
+	return p.Role == RoleAdmin
+}
+
+// CanAccessBooking reports whether the principal may read a booking, such
+// as a transaction, ticket or waitlist entry, of the customer with the given
+// email for an event of the given organizer.
+func (p *Principal) CanAccessBooking(customerEmail, organizer string) bool {
+	if p.Role == RoleCustomer {
+		return p.CanAccessCustomer(customerEmail)
+	}
+	return p.CanManageEvent(organizer)
+}
+
+// AuthService authenticates API keys and JWTs. API keys are for
+// server-to-server clients and are stored in the database; JWTs are for
+// users and are signed with HS256 using JWT_SECRET, either by this service
+// or by an identity provider sharing the secret. ADMIN_API_KEY remains a
+// built-in admin key.
+type AuthService struct {
+	db          *gorm.DB
+	adminAPIKey string
+	jwtSecret   []byte
+	issuer      string
+	audience    string
+	tokenTTL    time.Duration
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Role      string `json:"role" binding:"required"`
+	Organizer string `json:"organizer"`
+}
+
+// CreatedAPIKey is returned once on creation; the key cannot be read back
+// afterwards.
+type CreatedAPIKey struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+type IssueTokenRequest struct {
+	Subject    string `json:"subject" binding:"required"`
+	Role       string `json:"role" binding:"required"`
+	Email      string `json:"email"`
+	Organizer  string `json:"organizer"`
+	TTLMinutes int    `json:"ttl_minutes" binding:"gte=0"`
+}
+
+type IssuedToken struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func NewAuthService(db *gorm.DB, adminAPIKey, jwtSecret, issuer, audience string, tokenTTL time.Duration) (*AuthService, error) {
+	if jwtSecret != "" && len(jwtSecret) < minJWTSecretLength {
+		return nil, fmt.Errorf("JWT_SECRET must be at least %d bytes", minJWTSecretLength)
+	}
+
+	return &AuthService{
+		db:          db,
+		adminAPIKey: adminAPIKey,
+		jwtSecret:   []byte(jwtSecret),
+		issuer:      issuer,
+		audience:    audience,
+		tokenTTL:    tokenTTL,
+	}, nil
+}
+
+// IsAPIKey reports whether a bearer credential looks like an API key rather
+// than a JWT.
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyPrefix)
+}
+
+// AuthenticateAdminKey checks the built-in ADMIN_API_KEY. Every holder of
+// the key is the same subject, "admin"; claimedBy only records who the
+// caller says they are.
+func (as *AuthService) AuthenticateAdminKey(key, claimedBy string) (*Principal, error) {
+	if as.adminAPIKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(as.adminAPIKey)) != 1 {
+		return nil, &AuthError{Code: "INVALID_API_KEY", Message: "admin key is invalid"}
+	}
+	return &Principal{Method: AuthMethodAdminKey, Subject: adminKeySubject, Role: RoleAdmin, ClaimedBy: claimedBy}, nil
+}
+
+func (as *AuthService) AuthenticateAPIKey(key string) (*Principal, error) {
+	var apiKey models.APIKey
+	if err := as.db.Where("key_hash = ? AND revoked_at IS NULL", hashAPIKey(key)).
+		First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &AuthError{Code: "INVALID_API_KEY", Message: "API key is invalid or has been revoked"}
+		}
+		return nil, err
+	}
+
+	// Record usage at most once a minute per key.
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyUsageRefresh {
+		if err := as.db.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).
+			UpdateColumn("last_used_at", now).Error; err != nil {
+			log.Printf("Failed to record use of API key %s: %v", apiKey.ID, err)
+		}
+	}
+
+	return &Principal{
+		Method:    AuthMethodAPIKey,
+		Subject:   apiKey.Name,
+		Role:      apiKey.Role,
+		Organizer: apiKey.Organizer,
+		APIKeyID:  &apiKey.ID,
+	}, nil
+}
+
+func (as *AuthService) AuthenticateToken(token string) (*Principal, error) {
+	if len(as.jwtSecret) == 0 {
+		return nil, invalidToken("token authentication is not enabled")
+	}
+
+	claims, err := parseJWT(as.jwtSecret, token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if as.issuer != "" && claims.Issuer != as.issuer {
+		return nil, invalidToken("token issuer is not accepted")
+	}
+	if as.audience != "" && !claims.hasAudience(as.audience) {
+		return nil, invalidToken("token audience is not accepted")
+	}
+	if err := validatePrincipal(claims.Subject, claims.Role, claims.Email, claims.Organizer); err != nil {
+		return nil, invalidToken(err.Error())
+	}
+
+	return &Principal{
+		Method:    AuthMethodJWT,
+		Subject:   claims.Subject,
+		Role:      claims.Role,
+		Email:     claims.Email,
+		Organizer: claims.Organizer,
+	}, nil
+}
+
+// validatePrincipal checks the fields a role depends on: customers are
+// identified by email and organizers by the organizer name on their events.
+func validatePrincipal(subject, role, email, organizer string) error {
+	if subject == "" {
+		return errors.New("subject is required")
+	}
+	switch role {
+	case RoleAdmin, RoleGateStaff:
+	case RoleOrganizer:
+		if organizer == "" {
+			return errors.New("organizer is required for the organizer role")
+		}
+	case RoleCustomer:
+		if email == "" {
+			return errors.New("email is required for the customer role")
+		}
+	default:
+		return fmt.Errorf("role must be one of %s", strings.Join(Roles, ", "))
+	}
+	return nil
+}
+
+// IssueToken signs a JWT for a user, for deployments without a separate
+// identity provider.
+func (as *AuthService) IssueToken(req *IssueTokenRequest) (*IssuedToken, error) {
+	if len(as.jwtSecret) == 0 {
+		return nil, ErrTokensDisabled
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	req.Organizer = strings.TrimSpace(req.Organizer)
+	if err := validatePrincipal(req.Subject, req.Role, req.Email, req.Organizer); err != nil {
+		return nil, err
+	}
+
+	ttl := as.tokenTTL
+	if req.TTLMinutes > 0 {
+		ttl = time.Duration(req.TTLMinutes) * time.Minute
+	}
+	now := time.Now()
+	expiresAt := now.Add(ttl).Truncate(time.Second)
+
+	claims := &jwtClaims{
+		Role:      req.Role,
+		Email:     req.Email,
+		Organizer: req.Organizer,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   req.Subject,
+			Issuer:    as.issuer,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	if as.audience != "" {
+		claims.Audience = jwt.ClaimStrings{as.audience}
+	}
+
+	token, err := signJWT(as.jwtSecret, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &IssuedToken{Token: token, TokenType: "Bearer", ExpiresAt: expiresAt}, nil
+}
+
+func (as *AuthService) ListAPIKeys() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := as.db.Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CreateAPIKey creates a key for a server-to-server client. Customers sign
+// in as users, so keys cannot have the customer role.
+func (as *AuthService) CreateAPIKey(req *CreateAPIKeyRequest, createdBy string) (*CreatedAPIKey, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Organizer = strings.TrimSpace(req.Organizer)
+	if req.Role == RoleCustomer {
+		return nil, errors.New("API keys cannot have the customer role")
+	}
+	if err := validatePrincipal(req.Name, req.Role, "", req.Organizer); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+
+	apiKey := &models.APIKey{
+		Name:      req.Name,
+		Role:      req.Role,
+		Organizer: req.Organizer,
+		Prefix:    key[:len(apiKeyPrefix)+8],
+		KeyHash:   hashAPIKey(key),
+		CreatedBy: createdBy,
+	}
+	if err := as.db.Create(apiKey).Error; err != nil {
+		return nil, err
+	}
+
+	return &CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+func (as *AuthService) RevokeAPIKey(id uuid.UUID) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	if err := as.db.First(&apiKey, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if apiKey.RevokedAt != nil {
+		return &apiKey, nil
+	}
+
+	now := time.Now()
+	if err := as.db.Model(&apiKey).Update("revoked_at", now).Error; err != nil {
+		return nil, err
+	}
+	apiKey.RevokedAt = &now
+	return &apiKey, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"fmt"
+	"sermorpheus-engine-test/internal/config"
+	"sermorpheus-engine-test/internal/money"
+	"strconv"
+	"strings"
+)
+
+type Chain struct {
+	ID            int64    `json:"chain_id"`
+	Key           string   `json:"key"`
+	Name          string   `json:"name"`
+	RPCURL        string   `json:"-"`
+	RPCURLs       []string `json:"-"`
+	WSURL         string   `json:"-"`
+	Confirmations int      `json:"confirmations"`
+	NativeSymbol  string   `json:"native_symbol"`
+	// DepositAddress is the shared address all orders on the chain pay to in
+	// unique-amount mode.
+	DepositAddress string `json:"-"`
+	// NetworkFeeIDR is the gas cost passed on to customers by fee rules that
+	// pass the network fee through.
+	NetworkFeeIDR money.IDR `json:"network_fee_idr"`
+
+	tokens     map[string]*PaymentToken
+	tokenOrder []string
+}
+
+func (c *Chain) Tokens() []*PaymentToken {
+	tokens := make([]*PaymentToken, 0, len(c.tokenOrder))
+	for _, symbol := range c.tokenOrder {
+		tokens = append(tokens, c.tokens[symbol])
+	}
+	return tokens
+}
+
+func (c *Chain) Token(symbol string) (*PaymentToken, bool) {
+	token, exists := c.tokens[strings.ToUpper(symbol)]
+	return token, exists
+}
+
+// ChainRegistry holds the enabled chains and the payment tokens accepted on
+// each of them.
+type ChainRegistry struct {
+	chains       map[int64]*Chain
+	order        []int64
+	defaultChain *Chain
+	defaultToken string
+}
+
+func NewChainRegistry(cfg *config.Config) (*ChainRegistry, error) {
+	var enabledTokens []string
+	for _, name := range cfg.PaymentTokens {
+		if symbol := strings.ToUpper(strings.TrimSpace(name)); symbol != "" {
+			enabledTokens = append(enabledTokens, symbol)
+		}
+	}
+	if len(enabledTokens) == 0 {
+		return nil, fmt.Errorf("no payment tokens enabled")
+	}
+
+	registry := &ChainRegistry{
+		chains:       make(map[int64]*Chain),
+		defaultToken: strings.ToUpper(strings.TrimSpace(cfg.DefaultToken)),
+	}
+	offered := make(map[string]bool)
+
+	for _, chainCfg := range cfg.Chains {
+		if chainCfg.ChainID <= 0 {
+			return nil, fmt.Errorf("chain %s has no chain ID configured", chainCfg.Key)
+		}
+		if len(chainCfg.RPCURLs) == 0 {
+			return nil, fmt.Errorf("chain %s has no RPC URL configured", chainCfg.Key)
+		}
+		if _, exists := registry.chains[chainCfg.ChainID]; exists {
+			return nil, fmt.Errorf("chain ID %d is configured twice", chainCfg.ChainID)
+		}
+
+		chain := &Chain{
+			ID:             chainCfg.ChainID,
+			Key:            chainCfg.Key,
+			Name:           chainCfg.Name,
+			RPCURL:         chainCfg.RPCURL,
+			RPCURLs:        chainCfg.RPCURLs,
+			WSURL:          chainCfg.WSURL,
+			Confirmations:  chainCfg.Confirmations,
+			NativeSymbol:   chainCfg.NativeSymbol,
+			DepositAddress: chainCfg.DepositAddress,
+			NetworkFeeIDR:  money.IDR(chainCfg.NetworkFeeIDR),
+			tokens:         make(map[string]*PaymentToken),
+		}
+		if chain.Confirmations < 1 {
+			chain.Confirmations = 1
+		}
+
+		for _, symbol := range enabledTokens {
+			if _, exists := chain.tokens[symbol]; exists {
+				continue
+			}
+
+			token := &PaymentToken{Symbol: symbol, Name: tokenNames[symbol], ChainID: chain.ID}
+			if symbol == chain.NativeSymbol {
+				pair, exists := nativeCoinPairs[symbol]
+				if !exists {
+					return nil, fmt.Errorf("no price source for native coin %s on chain %s", symbol, chain.Key)
+				}
+				token.Decimals = 18
+				token.RateSource = string(pair)
+				token.RatePair = pair
+			} else if contract, exists := chainCfg.Contracts[symbol]; exists {
+				token.Contract = contract.Address
+				token.Decimals = contract.Decimals
+				token.RateSource = TokenRateSourcePeg
+			} else {
+				continue
+			}
+
+			chain.tokens[symbol] = token
+			chain.tokenOrder = append(chain.tokenOrder, symbol)
+			offered[symbol] = true
+		}
+
+		if len(chain.tokenOrder) == 0 {
+			return nil, fmt.Errorf("chain %s offers none of the enabled payment tokens", chain.Key)
+		}
+
+		registry.chains[chain.ID] = chain
+		registry.order = append(registry.order, chain.ID)
+
+		if chain.Key == cfg.DefaultChain || strconv.FormatInt(chain.ID, 10) == cfg.DefaultChain {
+			registry.defaultChain = chain
+		}
+	}
+
+	for _, symbol := range enabledTokens {
+		if !offered[symbol] {
+			return nil, fmt.Errorf("payment token %s is not available on any enabled chain", symbol)
+		}
+	}
+
+	if registry.defaultChain == nil {
+		return nil, fmt.Errorf("default chain %q is not enabled", cfg.DefaultChain)
+	}
+	if _, exists := registry.defaultChain.Token(registry.defaultToken); !exists {
+		return nil, fmt.Errorf("default payment token %q is not available on %s", cfg.DefaultToken, registry.defaultChain.Key)
+	}
+
+	return registry, nil
+}
+
+// Resolve returns the payment token for a booking. A zero chainID selects the
+// default chain and an empty symbol the default token (or the chain's first
+// token when the default is not offered there).
+func (r *ChainRegistry) Resolve(chainID int64, symbol string) (*PaymentToken, error) {
+	chain := r.defaultChain
+	if chainID != 0 {
+		var err error
+		if chain, err = r.Chain(chainID); err != nil {
+			return nil, err
+		}
+	}
+
+	if symbol == "" {
+		if token, exists := chain.Token(r.defaultToken); exists {
+			return token, nil
+		}
+		return chain.tokens[chain.tokenOrder[0]], nil
+	}
+
+	token, exists := chain.Token(symbol)
+	if !exists {
+		return nil, ErrTokenNotSupported
+	}
+	return token, nil
+}
+
+func (r *ChainRegistry) Chain(id int64) (*Chain, error) {
+	chain, exists := r.chains[id]
+	if !exists {
+		return nil, ErrChainNotSupported
+	}
+	return chain, nil
+}
+
+func (r *ChainRegistry) Chains() []*Chain {
+	chains := make([]*Chain, 0, len(r.order))
+	for _, id := range r.order {
+		chains = append(chains, r.chains[id])
+	}
+	return chains
+}
+
+func (r *ChainRegistry) Default() *Chain {
+	return r.defaultChain
+}
+
+func (r *ChainRegistry) DefaultToken() string {
+	return r.defaultToken
+}
+
+// PricedPairs lists the USDT-denominated pairs that must be fetched to price
+// the enabled non-pegged tokens.
+func (r *ChainRegistry) PricedPairs() []RatePair {
+	seen := make(map[RatePair]bool)
+	var pairs []RatePair
+	for _, chain := range r.Chains() {
+		for _, token := range chain.Tokens() {
+			if token.RatePair != "" && !seen[token.RatePair] {
+				seen[token.RatePair] = true
+				pairs = append(pairs, token.RatePair)
+			}
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,657 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	recentBlocks     = 20
+	maxBlocksPerScan = 100
+)
+
+var transferSig = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// ChainWatcher scans one chain for payments to the addresses of its pending
+// transactions. Payments are only confirmed once their block has the chain's
+// required number of confirmations; newer blocks are watched so checkout
+// pages can be told about a transfer while it gathers confirmations.
+type ChainWatcher struct {
+	db    *gorm.DB
+	chain *Chain
+	pool  *RPCPool
+
+	mu           sync.Mutex
+	cursorLoaded bool
+	lastScanned  uint64
+	lastSeenHead uint64
+	detected     map[string]*detectedTransfer
+}
+
+// detectedTransfer is a matching transfer in a block that is not yet deep
+// enough to confirm the payment.
+type detectedTransfer struct {
+	transactionID uuid.UUID
+	txHash        string
+	blockNumber   uint64
+	confirmations int
+	amount        string
+	token         string
+}
+
+// chainTransfer is a successful transfer of a payment token found in a block.
+type chainTransfer struct {
+	to     common.Address
+	from   common.Address
+	symbol string
+	amount *big.Int
+	txHash string
+}
+
+func newChainWatcher(db *gorm.DB, chain *Chain, pool *RPCPool) *ChainWatcher {
+	return &ChainWatcher{db: db, chain: chain, pool: pool, detected: make(map[string]*detectedTransfer)}
+}
+
+func (w *ChainWatcher) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := w.scanNewBlocks(); err != nil {
+				log.Printf("Failed to scan %s: %v", w.chain.Name, err)
+			}
+		}
+	}()
+}
+
+func (w *ChainWatcher) scanNewBlocks() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	head, safe, err := w.chainHead(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if !w.cursorLoaded {
+		if err := w.loadCursor(); err != nil {
+			return err
+		}
+	}
+	scannedBefore := w.lastScanned
+	defer func() {
+		if w.lastScanned != scannedBefore {
+			w.saveCursor()
+		}
+	}()
+
+	pending, err := w.pendingPayments()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		w.lastScanned = safe
+		w.lastSeenHead = head
+		clear(w.detected)
+		return nil
+	}
+
+	// A chain scanned for the first time starts at the last recentBlocks
+	// blocks. After that every block is scanned, from where the last run
+	// stopped, and a watcher that fell behind, for example during an RPC
+	// outage or a restart, catches up maxBlocksPerScan blocks per pass.
+	from := w.lastScanned + 1
+	if w.lastScanned == 0 {
+		from = safe - min(safe, recentBlocks-1)
+	}
+	to := min(safe, from+maxBlocksPerScan-1)
+
+	for blockNum := from; blockNum <= to; blockNum++ {
+		if err := w.checkBlock(blockNum, safe, pending); err != nil {
+			return err
+		}
+		w.lastScanned = blockNum
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if to == safe {
+		w.watchUnconfirmed(head, safe, pending)
+	}
+	return nil
+}
+
+// loadCursor resumes from the last block scanned before a restart.
+func (w *ChainWatcher) loadCursor() error {
+	var cursor models.ChainCursor
+	err := w.db.Where("chain_id = ?", w.chain.ID).Limit(1).Find(&cursor).Error
+	if err != nil {
+		return fmt.Errorf("failed to load the scan cursor: %w", err)
+	}
+	w.lastScanned = cursor.LastScanned
+	w.cursorLoaded = true
+	return nil
+}
+
+// saveCursor stores the last scanned block. A cursor that fails to save only
+// means some blocks are scanned again after a restart.
+func (w *ChainWatcher) saveCursor() {
+	cursor := models.ChainCursor{ChainID: w.chain.ID, LastScanned: w.lastScanned, UpdatedAt: time.Now()}
+	err := w.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_scanned", "updated_at"}),
+	}).Create(&cursor).Error
+	if err != nil {
+		log.Printf("Failed to save the scan cursor of %s: %v", w.chain.Name, err)
+	}
+}
+
+// watchUnconfirmed reports transfers to pending transactions in blocks past
+// safe, and their confirmation count as the chain grows. Each block is read
+// once; detections are dropped once the confirmed scan has passed their block.
+func (w *ChainWatcher) watchUnconfirmed(head, safe uint64, pending map[common.Address][]models.Transaction) {
+	for blockNum := max(safe, w.lastSeenHead) + 1; blockNum <= head; blockNum++ {
+		transfers, err := w.blockTransfers(context.Background(), blockNum, pending)
+		if err != nil {
+			log.Printf("Failed to read unconfirmed block %d on %s: %v", blockNum, w.chain.Name, err)
+			break
+		}
+
+		for _, transfer := range transfers {
+			transaction := findPaymentMatch(pending[transfer.to], transfer.symbol, money.NewUnits(transfer.amount))
+			if transaction == nil {
+				continue
+			}
+
+			key := transfer.txHash + ":" + transaction.ID.String()
+			if _, seen := w.detected[key]; seen {
+				continue
+			}
+			w.detected[key] = &detectedTransfer{
+				transactionID: transaction.ID,
+				txHash:        transfer.txHash,
+				blockNumber:   blockNum,
+				confirmations: int(head-blockNum) + 1,
+				amount:        money.NewUnits(transfer.amount).Format(transaction.TokenDecimals),
+				token:         transfer.symbol,
+			}
+			log.Printf("Detected unconfirmed payment for transaction %s on %s (tx: %s)", transaction.ID, w.chain.Name, transfer.txHash)
+			w.notifyProgress(PaymentTransferDetected, w.detected[key])
+		}
+		w.lastSeenHead = blockNum
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for key, transfer := range w.detected {
+		if transfer.blockNumber <= w.lastScanned {
+			delete(w.detected, key)
+			continue
+		}
+		confirmations := int(head-transfer.blockNumber) + 1
+		if confirmations != transfer.confirmations {
+			transfer.confirmations = confirmations
+			w.notifyProgress(PaymentConfirmations, transfer)
+		}
+	}
+}
+
+func (w *ChainWatcher) notifyProgress(updateType string, transfer *detectedTransfer) {
+	err := notifyPaymentStatus(w.db, PaymentStatusUpdate{
+		Type:                  updateType,
+		TransactionID:         transfer.transactionID,
+		Status:                "pending",
+		TxHash:                transfer.txHash,
+		BlockNumber:           transfer.blockNumber,
+		Confirmations:         transfer.confirmations,
+		RequiredConfirmations: w.chain.Confirmations,
+		Amount:                transfer.amount,
+		Token:                 transfer.token,
+	})
+	if err != nil {
+		log.Printf("Failed to publish %s for transaction %s: %v", updateType, transfer.transactionID, err)
+	}
+}
+
+// CheckTransaction scans recent blocks for a payment to a single transaction
+// and reports whether it is paid.
+func (w *ChainWatcher) CheckTransaction(transaction *models.Transaction) bool {
+	var existingTx models.Transaction
+	if err := w.db.Where("id = ? AND status = ?", transaction.ID, "paid").First(&existingTx).Error; err == nil {
+		log.Printf("Transaction %s already paid, skipping check", transaction.ID)
+		return true
+	}
+
+	check := &models.PaymentCheck{}
+	if err := w.scanForPayment(context.Background(), transaction, check); err != nil {
+		log.Printf("Failed to check %s for transaction %s: %v", w.chain.Name, transaction.ID, err)
+		return false
+	}
+	return check.Result == PaymentCheckPaid
+}
+
+// scanForPayment looks for the transaction's payment from the chain head back
+// through the last recentBlocks confirmed blocks, newest first, and records
+// the transfers to its payment address in check. A match in a confirmed block
+// confirms the payment; a match in a newer block is reported as awaiting
+// confirmations. It stops at the first match or when ctx is done.
+func (w *ChainWatcher) scanForPayment(ctx context.Context, transaction *models.Transaction, check *models.PaymentCheck) error {
+	head, safe, err := w.chainHead(ctx)
+	if err != nil {
+		return err
+	}
+
+	address := common.HexToAddress(transaction.PaymentAddress)
+	pending := map[common.Address][]models.Transaction{address: {*transaction}}
+
+	check.ToBlock = head
+	check.ConfirmedBlock = safe
+	check.FromBlock = safe - min(safe, recentBlocks-1)
+
+	log.Printf("Checking %s blocks %d-%d for address %s (expecting %s %s)",
+		w.chain.Name, check.FromBlock, head, transaction.PaymentAddress, transaction.TokenAmount.Format(transaction.TokenDecimals), transaction.PaymentToken)
+
+	for blockNum := head; blockNum >= check.FromBlock; blockNum-- {
+		transfers, err := w.blockTransfers(ctx, blockNum, pending)
+		if err != nil {
+			return err
+		}
+		check.BlocksScanned++
+		confirmations := int(head-blockNum) + 1
+
+		for _, transfer := range transfers {
+			if transfer.to != address {
+				continue
+			}
+
+			amount := money.NewUnits(transfer.amount)
+			candidate := models.PaymentCheckCandidate{
+				TxHash:        transfer.txHash,
+				BlockNumber:   blockNum,
+				From:          transfer.from.Hex(),
+				Token:         transfer.symbol,
+				Amount:        amount.String(),
+				Confirmations: confirmations,
+				Match:         PaymentCandidateMatched,
+			}
+			if token, ok := w.chain.Token(transfer.symbol); ok {
+				candidate.Amount = amount.Format(token.Decimals)
+			}
+			switch {
+			case !strings.EqualFold(transfer.symbol, transaction.PaymentToken):
+				candidate.Match = PaymentCandidateWrongToken
+			case !amount.Equal(transaction.TokenAmount):
+				candidate.Match = PaymentCandidateWrongAmount
+			}
+			if len(check.Candidates) < maxCheckCandidates {
+				check.Candidates = append(check.Candidates, candidate)
+			}
+			if candidate.Match != PaymentCandidateMatched {
+				continue
+			}
+
+			check.TxHash = transfer.txHash
+			if blockNum > safe {
+				check.Result = PaymentCheckAwaitingConfirmations
+				return nil
+			}
+			paid, err := w.confirmTransactionPayment(transaction.ID, transfer.txHash, transfer.from.Hex(), amount, confirmations)
+			if err != nil {
+				return err
+			}
+			check.Result = PaymentCheckPaid
+			if !paid {
+				check.Result = PaymentCheckLate
+			}
+			return nil
+		}
+
+		if blockNum == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	check.Result = PaymentCheckNotFound
+	return nil
+}
+
+// chainHead returns the latest block and the latest block with the required
+// number of confirmations.
+func (w *ChainWatcher) chainHead(ctx context.Context) (uint64, uint64, error) {
+	latest, err := w.pool.BlockNumber(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	confirmations := uint64(w.chain.Confirmations)
+	if latest+1 < confirmations {
+		return 0, 0, fmt.Errorf("chain height %d is below the required %d confirmations", latest, confirmations)
+	}
+	return latest, latest + 1 - confirmations, nil
+}
+
+func (w *ChainWatcher) pendingPayments() (map[common.Address][]models.Transaction, error) {
+	var transactions []models.Transaction
+	if err := w.db.Where("status = ? AND chain_id = ?", "pending", w.chain.ID).
+		Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	pending := make(map[common.Address][]models.Transaction)
+	for _, transaction := range transactions {
+		address := common.HexToAddress(transaction.PaymentAddress)
+		pending[address] = append(pending[address], transaction)
+	}
+	return pending, nil
+}
+
+// checkBlock confirms every pending transaction paid in blockNum and removes
+// it from pending. A payment that cannot be confirmed fails the block, so the
+// block is scanned again on the next pass.
+func (w *ChainWatcher) checkBlock(blockNum, safe uint64, pending map[common.Address][]models.Transaction) error {
+	transfers, err := w.blockTransfers(context.Background(), blockNum, pending)
+	if err != nil {
+		return err
+	}
+
+	confirmations := int(safe-blockNum) + w.chain.Confirmations
+	for _, transfer := range transfers {
+		if err := w.matchPayment(pending, transfer.to, transfer.symbol, transfer.amount, transfer.from, transfer.txHash, confirmations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockTransfers returns the successful token transfers in blockNum and the
+// native transfers to addresses in pending.
+func (w *ChainWatcher) blockTransfers(ctx context.Context, blockNum uint64, pending map[common.Address][]models.Transaction) ([]chainTransfer, error) {
+	block, err := w.pool.BlockByNumber(ctx, new(big.Int).SetUint64(blockNum))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %d: %w", blockNum, err)
+	}
+
+	signer := types.LatestSignerForChainID(big.NewInt(w.chain.ID))
+
+	contracts := make(map[common.Address]*PaymentToken)
+	for _, token := range w.chain.Tokens() {
+		if !token.IsNative() {
+			contracts[common.HexToAddress(token.Contract)] = token
+		}
+	}
+
+	var transfers []chainTransfer
+	for _, tx := range block.Transactions() {
+		if tx.To() == nil {
+			continue
+		}
+
+		_, nativePayment := pending[*tx.To()]
+		nativePayment = nativePayment && tx.Value().Sign() > 0
+		contractToken := contracts[*tx.To()]
+		if !nativePayment && contractToken == nil {
+			continue
+		}
+
+		// A receipt that cannot be read fails the whole block, so it is
+		// retried rather than passed over with a payment in it.
+		receipt, err := w.pool.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get receipt of %s in block %d: %w", tx.Hash().Hex(), blockNum, err)
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+
+		if nativePayment {
+			from, err := types.Sender(signer, tx)
+			if err != nil {
+				continue
+			}
+			transfers = append(transfers, chainTransfer{
+				to:     *tx.To(),
+				from:   from,
+				symbol: w.chain.NativeSymbol,
+				amount: tx.Value(),
+				txHash: tx.Hash().Hex(),
+			})
+			continue
+		}
+
+		for _, vLog := range receipt.Logs {
+			token, exists := contracts[vLog.Address]
+			if !exists || len(vLog.Topics) < 3 || vLog.Topics[0] != transferSig || len(vLog.Data) < 32 {
+				continue
+			}
+
+			transfers = append(transfers, chainTransfer{
+				to:     common.BytesToAddress(vLog.Topics[2].Bytes()),
+				from:   common.BytesToAddress(vLog.Topics[1].Bytes()),
+				symbol: token.Symbol,
+				amount: new(big.Int).SetBytes(vLog.Data[len(vLog.Data)-32:]),
+				txHash: vLog.TxHash.Hex(),
+			})
+		}
+	}
+
+	return transfers, nil
+}
+
+// matchPayment confirms the first pending transaction at the address that
+// expects exactly this amount of the token, compared in base units.
+func (w *ChainWatcher) matchPayment(pending map[common.Address][]models.Transaction, to common.Address, symbol string, rawAmount *big.Int, from common.Address, txHash string, confirmations int) error {
+	candidates := pending[to]
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	token, exists := w.chain.Token(symbol)
+	if !exists {
+		return nil
+	}
+	amount := money.NewUnits(rawAmount)
+
+	log.Printf("Found transfer on %s: %s %s to %s (tx: %s)", w.chain.Name, amount.Format(token.Decimals), symbol, to.Hex(), txHash)
+
+	transaction := findPaymentMatch(candidates, symbol, amount)
+	if transaction == nil {
+		return nil
+	}
+
+	log.Printf("Payment match found! Amount: %s %s, Processing transaction %s",
+		amount.Format(token.Decimals), symbol, transaction.ID)
+
+	if _, err := w.confirmTransactionPayment(transaction.ID, txHash, from.Hex(), amount, confirmations); err != nil {
+		return fmt.Errorf("failed to confirm transaction %s: %w", transaction.ID, err)
+	}
+
+	for i := range candidates {
+		if candidates[i].ID == transaction.ID {
+			pending[to] = append(candidates[:i], candidates[i+1:]...)
+			break
+		}
+	}
+	if len(pending[to]) == 0 {
+		delete(pending, to)
+	}
+	return nil
+}
+
+// findPaymentMatch returns the first candidate expecting exactly this amount
+// of the token, compared in base units.
+func findPaymentMatch(candidates []models.Transaction, symbol string, amount money.Units) *models.Transaction {
+	for i := range candidates {
+		if strings.EqualFold(candidates[i].PaymentToken, symbol) && amount.Equal(candidates[i].TokenAmount) {
+			return &candidates[i]
+		}
+	}
+	return nil
+}
+
+// confirmTransactionPayment marks a pending transaction paid and records the
+// transfer. It reports whether the payment confirmed the transaction: a
+// payment for a transaction that expired or was cancelled in the meantime is
+// recorded as late and refunded instead.
+func (w *ChainWatcher) confirmTransactionPayment(transactionID uuid.UUID, txHash, fromAddress string, amount money.Units, confirmations int) (bool, error) {
+	paid := false
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		var existingTx models.Transaction
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", transactionID).First(&existingTx).Error
+		if err != nil {
+			return fmt.Errorf("transaction not found: %w", err)
+		}
+
+		var existingBlockchainTx models.BlockchainTransaction
+		lookup := tx.Where("transaction_id = ? AND tx_hash = ?", transactionID, txHash).
+			Limit(1).Find(&existingBlockchainTx)
+		if lookup.Error != nil {
+			return fmt.Errorf("failed to look up blockchain transaction: %w", lookup.Error)
+		}
+		recorded := lookup.RowsAffected > 0
+
+		switch existingTx.Status {
+		case "paid":
+			log.Printf("Transaction %s already paid, skipping confirmation", transactionID)
+			paid = true
+			return nil
+		case "pending":
+			paid = true
+		default:
+			if recorded {
+				return nil
+			}
+			return w.recordLatePayment(tx, &existingTx, txHash, fromAddress, amount, confirmations)
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.Transaction{}).
+			Where("id = ?", transactionID).
+			Updates(map[string]interface{}{
+				"status":               "paid",
+				"payment_confirmed_at": &now,
+				"updated_at":           now,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+
+		if err := markSeatsSold(tx, transactionID); err != nil {
+			return fmt.Errorf("failed to mark seats as sold: %w", err)
+		}
+
+		if err := recordTransactionEvent(tx, TransactionPaid, transactionID); err != nil {
+			return fmt.Errorf("failed to record payment event: %w", err)
+		}
+		if err := notifyPaymentStatus(tx, PaymentStatusUpdate{
+			Type:                  PaymentPaid,
+			TransactionID:         transactionID,
+			Status:                "paid",
+			TxHash:                txHash,
+			Confirmations:         confirmations,
+			RequiredConfirmations: w.chain.Confirmations,
+			Amount:                amount.Format(existingTx.TokenDecimals),
+			Token:                 existingTx.PaymentToken,
+		}); err != nil {
+			return fmt.Errorf("failed to publish payment status: %w", err)
+		}
+
+		if recorded {
+			log.Printf("Blockchain transaction record already exists for tx %s", txHash)
+			return nil
+		}
+
+		blockchainTx := &models.BlockchainTransaction{
+			TransactionID: transactionID,
+			ChainID:       w.chain.ID,
+			TxHash:        txHash,
+			FromAddress:   fromAddress,
+			ToAddress:     existingTx.PaymentAddress,
+			Amount:        amount,
+			Token:         existingTx.PaymentToken,
+			TokenDecimals: existingTx.TokenDecimals,
+			Confirmations: confirmations,
+			Status:        "confirmed",
+		}
+		if err := tx.Create(blockchainTx).Error; err != nil {
+			return fmt.Errorf("failed to create blockchain transaction: %w", err)
+		}
+
+		log.Printf("Transaction %s confirmed on %s with tx hash %s, amount: %s %s", transactionID, w.chain.Name, txHash, amount.Format(existingTx.TokenDecimals), existingTx.PaymentToken)
+		return nil
+	})
+	return paid, err
+}
+
+// recordLatePayment handles a payment for a transaction that is no longer
+// pending. The transfer is recorded as late and a refund to the sender is
+// opened, which operators see in the refunds list and through the
+// transaction.refund_pending event.
+func (w *ChainWatcher) recordLatePayment(tx *gorm.DB, transaction *models.Transaction, txHash, fromAddress string, amount money.Units, confirmations int) error {
+	blockchainTx := &models.BlockchainTransaction{
+		TransactionID: transaction.ID,
+		ChainID:       w.chain.ID,
+		TxHash:        txHash,
+		FromAddress:   fromAddress,
+		ToAddress:     transaction.PaymentAddress,
+		Amount:        amount,
+		Token:         transaction.PaymentToken,
+		TokenDecimals: transaction.TokenDecimals,
+		Confirmations: confirmations,
+		Status:        "late",
+	}
+	if err := tx.Create(blockchainTx).Error; err != nil {
+		return fmt.Errorf("failed to create blockchain transaction: %w", err)
+	}
+
+	refund := &models.Refund{
+		TransactionID: transaction.ID,
+		EventID:       transaction.EventID,
+		CustomerID:    transaction.CustomerID,
+		USDTAmount:    transaction.USDTAmount,
+		ChainID:       w.chain.ID,
+		Token:         transaction.PaymentToken,
+		TokenAmount:   amount,
+		TokenDecimals: transaction.TokenDecimals,
+		ToAddress:     fromAddress,
+		Reason:        "payment arrived after the order was " + transaction.Status,
+		Status:        "pending",
+	}
+	if err := tx.Create(refund).Error; err != nil {
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	if transaction.Status == "expired" || transaction.Status == "cancelled" {
+		if err := tx.Model(&models.Transaction{}).
+			Where("id = ?", transaction.ID).
+			Update("status", "refund_pending").Error; err != nil {
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+	}
+	if err := recordTransactionEvent(tx, TransactionRefundPending, transaction.ID); err != nil {
+		return fmt.Errorf("failed to record refund event: %w", err)
+	}
+
+	log.Printf("Late payment for %s transaction %s on %s with tx hash %s, amount: %s %s; refund %s opened",
+		transaction.Status, transaction.ID, w.chain.Name, txHash, amount.Format(transaction.TokenDecimals), transaction.PaymentToken, refund.ID)
+	return nil
+}
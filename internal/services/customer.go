@@ -35,17 +35,27 @@ func (cs *CustomerService) GetCustomerByEmail(email string) (*models.Customer, e
 	return &customer, nil
 }
 
-func (cs *CustomerService) GetOrCreateCustomer(email, name, phone string) (*models.Customer, error) {
+// GetOrCreateCustomer finds the customer by email or creates them. A
+// supported locale, when given, becomes the language of their emails.
+func (cs *CustomerService) GetOrCreateCustomer(email, name, phone, locale string) (*models.Customer, error) {
+	locale = normalizeLocale(locale)
+
 	customer, err := cs.GetCustomerByEmail(email)
 	if err == nil {
+		if locale != "" && locale != customer.Locale {
+			if err := cs.db.Model(customer).Update("locale", locale).Error; err != nil {
+				return nil, err
+			}
+		}
 		return customer, nil
 	}
 
 	if err == gorm.ErrRecordNotFound {
 		newCustomer := &models.Customer{
-			Email: email,
-			Name:  name,
-			Phone: phone,
+			Email:  email,
+			Name:   name,
+			Phone:  phone,
+			Locale: locale,
 		}
 
 		if err := cs.CreateCustomer(newCustomer); err != nil {
@@ -1,23 +1,34 @@
 package services
 
 import (
+	"fmt"
 	"log"
 	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
+	"strings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// legacyChainID is the chain of payments made before the chain registry:
+// BSC testnet.
+const legacyChainID = 97
+
 type DatabaseService struct {
 	DB *gorm.DB
 }
 
-func NewDatabaseService(databaseURL string) *DatabaseService {
+func NewDatabaseService(databaseURL string, chains *ChainRegistry) *DatabaseService {
 	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	if err := migrateMoneyColumns(db, chains); err != nil {
+		log.Fatal("Failed to migrate money columns:", err)
+	}
+
 	err = db.AutoMigrate(
 		&models.Event{},
 		&models.Customer{},
@@ -26,11 +37,35 @@ func NewDatabaseService(databaseURL string) *DatabaseService {
 		&models.PaymentAddress{},
 		&models.USDTRate{},
 		&models.BlockchainTransaction{},
+		&models.Refund{},
+		&models.Seat{},
+		&models.WaitlistEntry{},
+		&models.Quote{},
+		&models.RateCandle{},
+		&models.RateOverride{},
+		&models.RateAuditLog{},
+		&models.FeeRule{},
+		&models.PromoCode{},
+		&models.PromoRedemption{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.WebhookAttempt{},
+		&models.OutboxEvent{},
+		&models.PaymentCheck{},
+		&models.ChainCursor{},
+		&models.EmailMessage{},
+		&models.APIKey{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	createIndexes(db)
+
+	if err := backfillPaymentDeadlines(db); err != nil {
+		log.Fatal("Failed to backfill payment deadlines:", err)
+	}
+
 	log.Println("Database connected and migrated successfully")
 	return &DatabaseService{DB: db}
 }
@@ -43,3 +78,138 @@ func (ds *DatabaseService) Close() {
 	}
 	sqlDB.Close()
 }
+
+func createIndexes(db *gorm.DB) {
+	statements := []string{
+		"CREATE INDEX IF NOT EXISTS idx_events_search ON events USING GIN (" + eventSearchVector + ")",
+		"CREATE INDEX IF NOT EXISTS idx_events_schedule_id ON events (schedule, id) WHERE deleted_at IS NULL",
+		"CREATE INDEX IF NOT EXISTS idx_events_price_id ON events (price_idr, id) WHERE deleted_at IS NULL",
+		"CREATE INDEX IF NOT EXISTS idx_events_created_at_id ON events (created_at, id) WHERE deleted_at IS NULL",
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_events_location_trgm ON events USING GIN (location gin_trgm_ops)",
+		// Backstop for unique-amount mode: two pending orders never expect the
+		// same amount of the same token at the same address.
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_pending_amount ON transactions (chain_id, payment_address, payment_token, token_amount) WHERE status = 'pending'",
+		// At most one payment check runs per transaction across all replicas.
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_checks_running ON payment_checks (transaction_id) WHERE status = 'running'",
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			log.Printf("Failed to create index: %v", err)
+		}
+	}
+}
+
+// backfillPaymentDeadlines gives transactions created before deadlines were
+// stored the 30-minute window they were created with.
+func backfillPaymentDeadlines(db *gorm.DB) error {
+	result := db.Exec("UPDATE transactions SET payment_deadline = payment_locked_at + interval '30 minutes' WHERE payment_deadline IS NULL AND payment_locked_at IS NOT NULL")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Backfilled the payment deadline of %d transactions", result.RowsAffected)
+	}
+	return nil
+}
+
+// migrateMoneyColumns converts a database created before money was stored as
+// integers: IDR amounts become whole rupiah and token amounts base units. It
+// runs once, before AutoMigrate, and is skipped when transactions already
+// have a token_decimals column. Databases from before multi-chain payments
+// lack the chain and token columns it reads, so those are added first with
+// the defaults AutoMigrate would give them: such rows were USDT on BSC testnet.
+func migrateMoneyColumns(db *gorm.DB, chains *ChainRegistry) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable("transactions") || migrator.HasColumn("transactions", "token_decimals") {
+		return nil
+	}
+
+	log.Println("Converting money columns to integer rupiah and token base units")
+
+	usdtUnits := func(column string) string {
+		return fmt.Sprintf("%s TYPE numeric(78,0) USING round(%s::numeric * 1e%d)", column, column, money.USDTDecimals)
+	}
+	tokenUnits := func(column string) string {
+		return fmt.Sprintf("%s TYPE numeric(78,0) USING round(%s::numeric * power(10::numeric, token_decimals))", column, column)
+	}
+	rate := func(column string) string {
+		return fmt.Sprintf("%s TYPE numeric(20,8) USING %s::numeric(20,8)", column, column)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"ALTER TABLE events ALTER COLUMN price_idr TYPE bigint USING round(price_idr)",
+		}
+
+		chainID := fmt.Sprintf("chain_id bigint DEFAULT %d", legacyChainID)
+		for _, table := range []struct {
+			name        string
+			tokenColumn string
+			columns     []string
+			alters      []string
+		}{
+			{"transactions", "payment_token", []string{
+				chainID, "payment_token text DEFAULT 'USDT'", "token_rate numeric", "token_amount numeric",
+			}, []string{
+				"total_idr TYPE bigint USING round(total_idr)", rate("usdt_rate"), rate("token_rate"),
+				tokenUnits("token_amount"), usdtUnits("usdt_amount"),
+			}},
+			{"quotes", "payment_token", []string{
+				chainID, "payment_token text DEFAULT 'USDT'", "token_rate numeric", "token_amount numeric",
+			}, []string{
+				"total_idr TYPE bigint USING round(total_idr)", rate("usdt_rate"), rate("token_rate"),
+				tokenUnits("token_amount"), usdtUnits("usdt_amount"),
+			}},
+			{"refunds", "token", []string{
+				chainID, "token text DEFAULT 'USDT'", "token_amount numeric",
+			}, []string{tokenUnits("token_amount"), usdtUnits("usdt_amount")}},
+			{"blockchain_transactions", "token", []string{
+				chainID, "token text DEFAULT 'USDT'",
+			}, []string{tokenUnits("amount")}},
+		} {
+			if !migrator.HasTable(table.name) {
+				continue
+			}
+			for _, column := range table.columns {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", table.name, column))
+			}
+			statements = append(statements,
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS token_decimals integer DEFAULT %d", table.name, money.USDTDecimals),
+				fmt.Sprintf("UPDATE %s SET token_decimals = %s", table.name, tokenDecimalsCase(chains, table.tokenColumn)),
+			)
+			if table.name != "blockchain_transactions" {
+				// Rows from before multi-token payments only carry the USDT amount.
+				statements = append(statements, fmt.Sprintf("UPDATE %s SET token_amount = usdt_amount WHERE token_amount IS NULL", table.name))
+			}
+			if table.name == "transactions" || table.name == "quotes" {
+				statements = append(statements, fmt.Sprintf("UPDATE %s SET token_rate = usdt_rate WHERE token_rate IS NULL", table.name))
+			}
+			for _, alter := range table.alters {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", table.name, alter))
+			}
+		}
+
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("%s: %w", statement, err)
+			}
+		}
+		return nil
+	})
+}
+
+// tokenDecimalsCase maps a row's chain and token to the token's decimals.
+// Tokens that are no longer enabled fall back to their usual decimals.
+func tokenDecimalsCase(chains *ChainRegistry, tokenColumn string) string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for _, chain := range chains.Chains() {
+		for _, token := range chain.Tokens() {
+			fmt.Fprintf(&b, " WHEN chain_id = %d AND %s = '%s' THEN %d", chain.ID, tokenColumn, token.Symbol, token.Decimals)
+		}
+	}
+	fmt.Fprintf(&b, " WHEN %s IN ('BNB', 'ETH', 'POL', 'BUSD') THEN 18 ELSE %d END", tokenColumn, money.USDTDecimals)
+	return b.String()
+}
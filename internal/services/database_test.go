@@ -0,0 +1,173 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// baselineSchema is the schema of scripts/init.sql before money was stored
+// as integers and before multi-chain payments.
+var baselineSchema = []string{
+	`CREATE TABLE events (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		name VARCHAR(255) NOT NULL,
+		description TEXT,
+		location VARCHAR(255) NOT NULL,
+		schedule TIMESTAMP WITH TIME ZONE NOT NULL,
+		price_idr DECIMAL(15,2) NOT NULL,
+		quota INTEGER NOT NULL,
+		available_quota INTEGER NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		deleted_at TIMESTAMP WITH TIME ZONE
+	)`,
+	`CREATE TABLE customers (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		email VARCHAR(255) UNIQUE NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		phone VARCHAR(50),
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+	`CREATE TABLE transactions (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		customer_id UUID NOT NULL REFERENCES customers(id),
+		event_id UUID NOT NULL REFERENCES events(id),
+		quantity INTEGER NOT NULL,
+		total_idr DECIMAL(15,2) NOT NULL,
+		usdt_rate DECIMAL(15,6) NOT NULL,
+		usdt_amount DECIMAL(15,6) NOT NULL,
+		payment_address VARCHAR(42),
+		status VARCHAR(20) DEFAULT 'pending',
+		payment_locked_at TIMESTAMP WITH TIME ZONE,
+		payment_confirmed_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+	`CREATE TABLE blockchain_transactions (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		transaction_id UUID NOT NULL REFERENCES transactions(id),
+		tx_hash VARCHAR(66) UNIQUE,
+		from_address VARCHAR(42),
+		to_address VARCHAR(42),
+		amount DECIMAL(15,6),
+		confirmations INTEGER DEFAULT 0,
+		status VARCHAR(20) DEFAULT 'pending',
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+}
+
+// openTestDatabase connects to TEST_DATABASE_URL inside a fresh schema that
+// is dropped when the test ends. Tests that need it are skipped when the
+// variable is not set.
+func openTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(databaseURL), config)
+	if err != nil {
+		t.Fatalf("failed to connect to the test database: %v", err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	parsed, err := url.Parse(databaseURL)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL must be a postgres:// URL: %v", err)
+	}
+	query := parsed.Query()
+	query.Set("search_path", schema)
+	parsed.RawQuery = query.Encode()
+
+	db, err := gorm.Open(postgres.Open(parsed.String()), config)
+	if err != nil {
+		t.Fatalf("failed to connect to the test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestMigrateMoneyColumnsFromBaseline(t *testing.T) {
+	db := openTestDatabase(t)
+
+	statements := append(append([]string{}, baselineSchema...),
+		`INSERT INTO events (id, name, location, schedule, price_idr, quota, available_quota)
+			VALUES ('00000000-0000-0000-0000-000000000001', 'Conference', 'Jakarta', NOW(), 500000.40, 100, 99)`,
+		`INSERT INTO customers (id, email, name) VALUES ('00000000-0000-0000-0000-000000000002', 'buyer@example.com', 'Buyer')`,
+		`INSERT INTO transactions (id, customer_id, event_id, quantity, total_idr, usdt_rate, usdt_amount, status)
+			VALUES ('00000000-0000-0000-0000-000000000003', '00000000-0000-0000-0000-000000000002',
+				'00000000-0000-0000-0000-000000000001', 2, 1000000.00, 16250.123456, 61.538456, 'paid')`,
+		`INSERT INTO blockchain_transactions (transaction_id, tx_hash, amount, status)
+			VALUES ('00000000-0000-0000-0000-000000000003', '0xabc', 61.538456, 'confirmed')`,
+	)
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("failed to set up the baseline schema: %v", err)
+		}
+	}
+
+	if err := migrateMoneyColumns(db, &ChainRegistry{}); err != nil {
+		t.Fatalf("migrateMoneyColumns returned error: %v", err)
+	}
+
+	var (
+		totalIDR, chainID                              int64
+		usdtRate, usdtAmount, token, tokenRate, amount string
+		tokenDecimals                                  int
+	)
+	if err := db.Raw(`SELECT total_idr, usdt_rate::text, usdt_amount::text, chain_id, payment_token,
+		token_rate::text, token_amount::text, token_decimals FROM transactions`).Row().
+		Scan(&totalIDR, &usdtRate, &usdtAmount, &chainID, &token, &tokenRate, &amount, &tokenDecimals); err != nil {
+		t.Fatal(err)
+	}
+	if totalIDR != 1000000 || usdtRate != "16250.12345600" || usdtAmount != "61538456" || chainID != legacyChainID ||
+		token != "USDT" || tokenRate != "16250.12345600" || amount != "61538456" || tokenDecimals != 6 {
+		t.Errorf("transaction = total %d, usdt rate %s, usdt amount %s, chain %d, token %s, token rate %s, token amount %s, decimals %d",
+			totalIDR, usdtRate, usdtAmount, chainID, token, tokenRate, amount, tokenDecimals)
+	}
+
+	var priceIDR int64
+	if err := db.Raw("SELECT price_idr FROM events").Row().Scan(&priceIDR); err != nil {
+		t.Fatal(err)
+	}
+	if priceIDR != 500000 {
+		t.Errorf("price_idr = %d, want 500000", priceIDR)
+	}
+
+	if err := db.Raw("SELECT amount::text, chain_id, token, token_decimals FROM blockchain_transactions").Row().
+		Scan(&amount, &chainID, &token, &tokenDecimals); err != nil {
+		t.Fatal(err)
+	}
+	if amount != "61538456" || chainID != legacyChainID || token != "USDT" || tokenDecimals != 6 {
+		t.Errorf("blockchain transaction = amount %s, chain %d, token %s, decimals %d", amount, chainID, token, tokenDecimals)
+	}
+
+	// A converted database is left alone.
+	if err := migrateMoneyColumns(db, &ChainRegistry{}); err != nil {
+		t.Fatalf("second migrateMoneyColumns returned error: %v", err)
+	}
+}
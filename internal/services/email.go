@@ -0,0 +1,257 @@
+package services
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sermorpheus-engine-test/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP connection security.
+const (
+	SMTPTLSNone     = "none"
+	SMTPTLSStartTLS = "starttls"
+	SMTPTLSImplicit = "tls"
+)
+
+// SMTPSender sends emails through an SMTP server. With starttls the server
+// must offer STARTTLS; the message is never sent in the clear. Credentials
+// are only sent over TLS, or to a server on localhost.
+type SMTPSender struct {
+	host     string
+	port     int
+	username string
+	password string
+	tlsMode  string
+	timeout  time.Duration
+	from     mail.Address
+}
+
+func NewSMTPSender(host string, port int, username, password, tlsMode string, timeout time.Duration, from string) (*SMTPSender, error) {
+	if host == "" {
+		return nil, errors.New("SMTP_HOST cannot be empty")
+	}
+	if port < 1 || port > 65535 {
+		return nil, fmt.Errorf("SMTP_PORT %d is out of range", port)
+	}
+	tlsMode = strings.ToLower(strings.TrimSpace(tlsMode))
+	switch tlsMode {
+	case SMTPTLSNone, SMTPTLSStartTLS, SMTPTLSImplicit:
+	default:
+		return nil, fmt.Errorf("SMTP_TLS must be none, starttls or tls, got %q", tlsMode)
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("EMAIL_FROM is not a valid address: %w", err)
+	}
+
+	return &SMTPSender{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		tlsMode:  tlsMode,
+		timeout:  timeout,
+		from:     *sender,
+	}, nil
+}
+
+// Send delivers one message. The whole conversation shares a single
+// timeout.
+func (s *SMTPSender) Send(message *models.EmailMessage) error {
+	body, err := s.compose(message)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	dialer := &net.Dialer{Timeout: s.timeout}
+	var conn net.Conn
+	if s.tlsMode == SMTPTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: s.host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if s.tlsMode == SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not offer STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(message.ToAddress); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// isPermanentSMTPError reports whether the server rejected the message in a
+// way retrying will not fix, such as an unknown mailbox.
+func isPermanentSMTPError(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500
+}
+
+// compose renders the message as MIME: a text and an HTML alternative,
+// wrapped together with any attachments. The Message-ID is derived from the
+// message ID, so retries of one message share it.
+func (s *SMTPSender) compose(message *models.EmailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+
+	domain := "localhost"
+	if at := strings.LastIndex(s.from.Address, "@"); at >= 0 {
+		domain = s.from.Address[at+1:]
+	}
+	to := mail.Address{Name: message.ToName, Address: message.ToAddress}
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", s.from.String())
+	header.Set("To", to.String())
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", message.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", "<"+message.ID.String()+"@"+domain+">")
+	header.Set("MIME-Version", "1.0")
+
+	alternative, boundary, err := composeAlternative(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(message.Attachments) == 0 {
+		header.Set("Content-Type", "multipart/alternative; boundary="+boundary)
+		writeMIMEHeader(&buf, header)
+		buf.Write(alternative)
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	header.Set("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	writeMIMEHeader(&buf, header)
+
+	part, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + boundary},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(alternative); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range message.Attachments {
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(attachment.ContentType, map[string]string{"name": attachment.Filename})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64Lines(part, attachment.Content); err != nil {
+			return nil, err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func composeAlternative(message *models.EmailMessage) ([]byte, string, error) {
+	var buf bytes.Buffer
+	alternative := multipart.NewWriter(&buf)
+
+	bodies := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", message.TextBody},
+		{"text/html; charset=utf-8", message.HTMLBody},
+	}
+	for _, body := range bodies {
+		part, err := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {body.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err := io.WriteString(qp, body.content); err != nil {
+			return nil, "", err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := alternative.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), alternative.Boundary(), nil
+}
+
+func writeMIMEHeader(w io.Writer, header textproto.MIMEHeader) {
+	for _, key := range []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"} {
+		fmt.Fprintf(w, "%s: %s\r\n", key, header.Get(key))
+	}
+	io.WriteString(w, "\r\n")
+}
+
+// writeBase64Lines writes content as base64 in lines of 76 characters, the
+// most RFC 2045 allows.
+func writeBase64Lines(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/email/*.tmpl
+var emailTemplateFS embed.FS
+
+// emailCatalog holds the translated strings used by the email templates,
+// by locale. Strings may contain fmt verbs filled from the template. English
+// is the fallback for keys a locale lacks.
+var emailCatalog = map[string]map[string]string{
+	"en": {
+		"subject.payment_instructions": "Complete your payment for %s",
+		"subject.payment_received":     "Your tickets for %s",
+		"subject.expiry_warning":       "Your reservation for %s expires in %d minutes",
+		"subject.refund":               "Refund for %s",
+		"subject.waitlist_offer":       "Tickets for %s are held for you",
+
+		"greeting": "Hi %s,",
+		"footer":   "This email is about order %s.",
+
+		"footer.waitlist": "This email is about waitlist entry %s.",
+
+		"label.event":    "Event",
+		"label.date":     "Date",
+		"label.location": "Location",
+		"label.tickets":  "Tickets",
+		"label.total":    "Total",
+		"label.amount":   "Amount to send",
+		"label.network":  "Network",
+		"label.address":  "Payment address",
+		"label.deadline": "Pay before",
+		"label.tx_hash":  "Transaction hash",
+		"label.ticket":   "Ticket",
+		"label.seat":     "Seat",
+		"label.holder":   "Name",
+		"label.order":    "Order",
+		"label.refund":   "Refund",
+		"label.to":       "Refunded to",
+		"label.reason":   "Reason",
+		"label.hold":     "Book before",
+		"label.waitlist": "Waitlist entry",
+
+		"instructions.intro":  "Thanks for booking %d ticket(s) for %s. Send the amount below to complete your order.",
+		"instructions.exact":  "Send exactly this amount of this token, in a single transfer on the network shown. A different amount, token or network will not be matched to your order.",
+		"instructions.expiry": "Your tickets are held until the deadline. After that the reservation is released.",
+
+		"received.intro": "We have received your payment for %s. Your e-tickets are attached; show them at the entrance.",
+
+		"warning.intro":        "Your reservation for %s expires at %s and we have not received your payment yet.",
+		"warning.already_paid": "If you have already sent the payment, there is nothing to do: it is confirmed as soon as the network confirms the transfer.",
+
+		"refund.intro": "%s has been cancelled, so we have refunded your payment.",
+		"refund.note":  "The refund was sent to the address your payment came from. There is nothing you need to do.",
+
+		"waitlist.intro": "Good news: %d ticket(s) for %s have become available and are held for you until %s.",
+		"waitlist.book":  "To buy them, book the same number of tickets with your waitlist entry ID before the hold ends. After that the tickets go to the next person on the waitlist.",
+
+		"ticket.title": "E-ticket",
+		"ticket.note":  "Show this code at the entrance. Each code admits one person, once.",
+	},
+	"id": {
+		"subject.payment_instructions": "Selesaikan pembayaran untuk %s",
+		"subject.payment_received":     "Tiket Anda untuk %s",
+		"subject.expiry_warning":       "Reservasi Anda untuk %s berakhir dalam %d menit",
+		"subject.refund":               "Pengembalian dana untuk %s",
+		"subject.waitlist_offer":       "Tiket %s ditahan untuk Anda",
+
+		"greeting": "Halo %s,",
+		"footer":   "Email ini terkait pesanan %s.",
+
+		"footer.waitlist": "Email ini terkait entri daftar tunggu %s.",
+
+		"label.event":    "Acara",
+		"label.date":     "Tanggal",
+		"label.location": "Lokasi",
+		"label.tickets":  "Tiket",
+		"label.total":    "Total",
+		"label.amount":   "Jumlah yang dikirim",
+		"label.network":  "Jaringan",
+		"label.address":  "Alamat pembayaran",
+		"label.deadline": "Bayar sebelum",
+		"label.tx_hash":  "Hash transaksi",
+		"label.ticket":   "Tiket",
+		"label.seat":     "Kursi",
+		"label.holder":   "Nama",
+		"label.order":    "Pesanan",
+		"label.refund":   "Pengembalian dana",
+		"label.to":       "Dikembalikan ke",
+		"label.reason":   "Alasan",
+		"label.hold":     "Pesan sebelum",
+		"label.waitlist": "Entri daftar tunggu",
+
+		"instructions.intro":  "Terima kasih telah memesan %d tiket untuk %s. Kirim jumlah di bawah ini untuk menyelesaikan pesanan Anda.",
+		"instructions.exact":  "Kirim jumlah dan token yang persis sama, dalam satu transfer di jaringan yang tertera. Jumlah, token, atau jaringan yang berbeda tidak akan dicocokkan dengan pesanan Anda.",
+		"instructions.expiry": "Tiket Anda ditahan sampai batas waktu. Setelah itu reservasi dilepas.",
+
+		"received.intro": "Pembayaran Anda untuk %s telah kami terima. E-tiket terlampir; tunjukkan di pintu masuk.",
+
+		"warning.intro":        "Reservasi Anda untuk %s berakhir pada %s dan pembayaran Anda belum kami terima.",
+		"warning.already_paid": "Jika Anda sudah mengirim pembayaran, tidak perlu melakukan apa pun: pembayaran dikonfirmasi segera setelah jaringan mengonfirmasi transfer.",
+
+		"refund.intro": "%s dibatalkan, sehingga pembayaran Anda telah kami kembalikan.",
+		"refund.note":  "Dana telah dikirim ke alamat asal pembayaran Anda. Anda tidak perlu melakukan apa pun.",
+
+		"waitlist.intro": "Kabar baik: %d tiket untuk %s kini tersedia dan ditahan untuk Anda sampai %s.",
+		"waitlist.book":  "Untuk membelinya, pesan jumlah tiket yang sama dengan ID entri daftar tunggu Anda sebelum masa tahan berakhir. Setelah itu tiket diberikan kepada orang berikutnya di daftar tunggu.",
+
+		"ticket.title": "E-tiket",
+		"ticket.note":  "Tunjukkan kode ini di pintu masuk. Setiap kode berlaku untuk satu orang, satu kali.",
+	},
+}
+
+// normalizeLocale maps a requested locale such as "id-ID" to a supported
+// one, or returns "" if none matches.
+func normalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	if _, ok := emailCatalog[locale]; ok {
+		return locale
+	}
+	return ""
+}
+
+func translate(locale, key string, args ...interface{}) string {
+	format, ok := emailCatalog[locale][key]
+	if !ok {
+		format, ok = emailCatalog["en"][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// emailTemplates renders each kind of email from
+// templates/email/<kind>.txt.tmpl, which defines "subject" and "body", and
+// <kind>.html.tmpl, which defines the "content" of the shared HTML layout.
+// Templates look up strings with {{t "key" args...}}.
+type emailTemplates struct {
+	text   map[string]*texttemplate.Template
+	html   map[string]*htmltemplate.Template
+	ticket *htmltemplate.Template
+}
+
+func loadEmailTemplates(kinds []string) (*emailTemplates, error) {
+	placeholder := map[string]interface{}{"t": func(string, ...interface{}) string { return "" }}
+
+	templates := &emailTemplates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+	for _, kind := range kinds {
+		text, err := texttemplate.New(kind).Funcs(placeholder).
+			ParseFS(emailTemplateFS, "templates/email/"+kind+".txt.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		html, err := htmltemplate.New(kind).Funcs(placeholder).
+			ParseFS(emailTemplateFS, "templates/email/layout.html.tmpl", "templates/email/"+kind+".html.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		templates.text[kind] = text
+		templates.html[kind] = html
+	}
+
+	ticket, err := htmltemplate.New("ticket").Funcs(placeholder).
+		ParseFS(emailTemplateFS, "templates/email/ticket.html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	templates.ticket = ticket
+	return templates, nil
+}
+
+// render returns the subject and the text and HTML bodies of an email.
+func (et *emailTemplates) render(kind, locale string, data interface{}) (string, string, string, error) {
+	funcs := map[string]interface{}{
+		"t": func(key string, args ...interface{}) string { return translate(locale, key, args...) },
+	}
+
+	text, err := et.text[kind].Clone()
+	if err != nil {
+		return "", "", "", err
+	}
+	text.Funcs(funcs)
+	var subject, textBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", "", err
+	}
+	if err := text.ExecuteTemplate(&textBody, "body", data); err != nil {
+		return "", "", "", err
+	}
+
+	html, err := et.html[kind].Clone()
+	if err != nil {
+		return "", "", "", err
+	}
+	html.Funcs(funcs)
+	var htmlBody bytes.Buffer
+	if err := html.ExecuteTemplate(&htmlBody, "layout", data); err != nil {
+		return "", "", "", err
+	}
+
+	return strings.TrimSpace(subject.String()), textBody.String(), htmlBody.String(), nil
+}
+
+// renderTicket returns a standalone HTML e-ticket.
+func (et *emailTemplates) renderTicket(locale string, data interface{}) ([]byte, error) {
+	ticket, err := et.ticket.Clone()
+	if err != nil {
+		return nil, err
+	}
+	ticket.Funcs(map[string]interface{}{
+		"t": func(key string, args ...interface{}) string { return translate(locale, key, args...) },
+	})
+
+	var buf bytes.Buffer
+	if err := ticket.ExecuteTemplate(&buf, "ticket", data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
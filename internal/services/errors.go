@@ -0,0 +1,51 @@
+package services
+
+type BookingError struct {
+	Code    string
+	Message string
+}
+
+func (e *BookingError) Error() string {
+	return e.Message
+}
+
+// ValidationError is returned when a request to create or change a resource
+// is invalid.
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(message string) *ValidationError {
+	return &ValidationError{Message: message}
+}
+
+var (
+	ErrEventNotFound       = &BookingError{Code: "EVENT_NOT_FOUND", Message: "event not found"}
+	ErrEventCancelled      = &BookingError{Code: "EVENT_CANCELLED", Message: "event has been cancelled"}
+	ErrSalesNotStarted     = &BookingError{Code: "SALES_NOT_STARTED", Message: "ticket sales have not started yet"}
+	ErrSalesEnded          = &BookingError{Code: "SALES_ENDED", Message: "ticket sales for this event have ended"}
+	ErrMaxPerTransaction   = &BookingError{Code: "MAX_PER_TRANSACTION_EXCEEDED", Message: "quantity exceeds the maximum tickets per transaction"}
+	ErrMaxPerCustomer      = &BookingError{Code: "MAX_PER_CUSTOMER_EXCEEDED", Message: "quantity exceeds the maximum tickets per customer"}
+	ErrInsufficientTickets = &BookingError{Code: "INSUFFICIENT_TICKETS", Message: "insufficient tickets available"}
+	ErrRateUnavailable     = &BookingError{Code: "RATE_UNAVAILABLE", Message: "failed to get exchange rate"}
+	ErrNoPaymentAddress    = &BookingError{Code: "NO_PAYMENT_ADDRESS", Message: "no payment address available"}
+	ErrSeatSelection       = &BookingError{Code: "SEAT_SELECTION_REQUIRED", Message: "select exactly one seat per ticket"}
+	ErrSeatingNotSupported = &BookingError{Code: "SEATING_NOT_SUPPORTED", Message: "event does not use assigned seating"}
+	ErrSeatNotFound        = &BookingError{Code: "SEAT_NOT_FOUND", Message: "one or more seats do not exist for this event"}
+	ErrSeatUnavailable     = &BookingError{Code: "SEAT_UNAVAILABLE", Message: "one or more seats are no longer available"}
+	ErrWaitlistOffer       = &BookingError{Code: "WAITLIST_OFFER_INVALID", Message: "waitlist offer is invalid or has expired"}
+	ErrQuoteInvalid        = &BookingError{Code: "QUOTE_INVALID", Message: "quote does not match this booking or has already been used"}
+	ErrQuoteExpired        = &BookingError{Code: "QUOTE_EXPIRED", Message: "quote has expired"}
+	ErrTokenNotSupported   = &BookingError{Code: "TOKEN_NOT_SUPPORTED", Message: "payment token is not supported"}
+	ErrChainNotSupported   = &BookingError{Code: "CHAIN_NOT_SUPPORTED", Message: "chain is not supported"}
+	ErrPromoInvalid        = &BookingError{Code: "PROMO_CODE_INVALID", Message: "promo code does not exist or has been disabled"}
+	ErrPromoNotActive      = &BookingError{Code: "PROMO_CODE_NOT_ACTIVE", Message: "promo code is not valid at this time"}
+	ErrPromoNotApplicable  = &BookingError{Code: "PROMO_CODE_NOT_APPLICABLE", Message: "promo code does not apply to this booking"}
+	ErrPromoExhausted      = &BookingError{Code: "PROMO_CODE_EXHAUSTED", Message: "promo code has reached its usage limit"}
+	ErrPromoCustomerLimit  = &BookingError{Code: "PROMO_CODE_CUSTOMER_LIMIT", Message: "promo code has already been used the maximum number of times by this customer"}
+	ErrNoUniqueAmount      = &BookingError{Code: "NO_UNIQUE_AMOUNT", Message: "no unique payment amount is available right now, try again shortly"}
+)
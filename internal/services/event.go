@@ -1,22 +1,86 @@
 package services
 
 import (
-	"errors"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type EventService struct {
-	db *gorm.DB
+	db             *gorm.DB
+	quotaListeners []func(eventID uuid.UUID)
+}
+
+type UpdateEventRequest struct {
+	Name        *string
+	Description *string
+	Location    *string
+	Organizer   *string
+	Schedule    *time.Time
+	PriceIDR    *money.IDR
+	Quota       *int
+
+	SaleStartAt              *time.Time
+	SaleEndAt                *time.Time
+	ClearSaleStartAt         bool
+	ClearSaleEndAt           bool
+	MaxTicketsPerTransaction *int
+	MaxTicketsPerCustomer    *int
+}
+
+type EventFilter struct {
+	Query     string
+	Location  string
+	From      *time.Time
+	To        *time.Time
+	MinPrice  *money.IDR
+	MaxPrice  *money.IDR
+	Available *bool
+	Sort      string
+	Limit     int
+	Offset    int
+	Cursor    string
+}
+
+type EventPage struct {
+	Events     []models.Event `json:"events"`
+	Total      int64          `json:"total"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type EventCancellation struct {
+	Event                 *models.Event   `json:"event"`
+	CancelledTransactions int             `json:"cancelled_transactions"`
+	Refunds               []models.Refund `json:"refunds"`
 }
 
 func NewEventService(db *gorm.DB) *EventService {
 	return &EventService{db: db}
 }
 
+func (es *EventService) OnQuotaRestored(listener func(eventID uuid.UUID)) {
+	es.quotaListeners = append(es.quotaListeners, listener)
+}
+
+func (es *EventService) notifyQuotaRestored(eventID uuid.UUID) {
+	for _, listener := range es.quotaListeners {
+		listener(eventID)
+	}
+}
+
 func (es *EventService) CreateEvent(event *models.Event) error {
+	if err := validateBookingRules(event); err != nil {
+		return err
+	}
+
 	event.AvailableQuota = event.Quota
 
 	if err := es.db.Create(event).Error; err != nil {
@@ -33,53 +97,476 @@ func (es *EventService) GetEventByID(id uuid.UUID) (*models.Event, error) {
 	return &event, nil
 }
 
-func (es *EventService) GetEvents(limit, offset int) ([]models.Event, error) {
+func (es *EventService) GetEvents(filter *EventFilter) (*EventPage, error) {
+	sort, ok := eventSorts[filter.Sort]
+	if !ok {
+		return nil, invalid(fmt.Sprintf("invalid sort %q", filter.Sort))
+	}
+
+	query := es.applyEventFilter(es.db.Model(&models.Event{}), filter)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeEventCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != filter.Sort {
+			return nil, invalid("cursor does not match sort order")
+		}
+
+		value, err := sort.parse(cursor.Value)
+		if err != nil {
+			return nil, invalid("invalid cursor")
+		}
+
+		op := ">"
+		if sort.desc {
+			op = "<"
+		}
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", sort.column, op), value, cursor.ID)
+	} else if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	direction := "ASC"
+	if sort.desc {
+		direction = "DESC"
+	}
+
 	var events []models.Event
-	query := es.db.Order("created_at DESC")
+	if err := query.
+		Order(fmt.Sprintf("%s %s, id %s", sort.column, direction, direction)).
+		Limit(filter.Limit + 1).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	page := &EventPage{Total: total}
+	if len(events) > filter.Limit {
+		events = events[:filter.Limit]
+		last := events[len(events)-1]
+		page.NextCursor = encodeEventCursor(eventCursor{
+			Sort:  filter.Sort,
+			Value: sort.format(&last),
+			ID:    last.ID,
+		})
+	}
+	page.Events = events
+
+	return page, nil
+}
+
+func (es *EventService) applyEventFilter(query *gorm.DB, filter *EventFilter) *gorm.DB {
+	if filter.Query != "" {
+		query = query.Where(eventSearchVector+" @@ plainto_tsquery('simple', ?)", filter.Query)
+	}
+	if filter.Location != "" {
+		query = query.Where("location ILIKE ?", "%"+filter.Location+"%")
+	}
+	if filter.From != nil {
+		query = query.Where("schedule >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("schedule <= ?", *filter.To)
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price_idr >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price_idr <= ?", *filter.MaxPrice)
+	}
+	if filter.Available != nil {
+		if *filter.Available {
+			query = query.Where("available_quota > 0 AND status = ?", "active")
+		} else {
+			query = query.Where("available_quota = 0 OR status <> ?", "active")
+		}
+	}
+	return query
+}
 
-	if limit > 0 {
-		query = query.Limit(limit)
+// reserveEventQuota takes quantity tickets from an event's available quota.
+// It runs inside the booking's transaction, so the quota comes back if any
+// later step of the booking fails.
+func reserveEventQuota(tx *gorm.DB, eventID uuid.UUID, quantity int) error {
+	var event models.Event
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&event, "id = ?", eventID).Error; err != nil {
+		return err
 	}
-	if offset > 0 {
-		query = query.Offset(offset)
+
+	if event.Status == "cancelled" {
+		return ErrEventCancelled
 	}
 
-	if err := query.Find(&events).Error; err != nil {
+	result := tx.Model(&models.Event{}).
+		Where("id = ? AND available_quota >= ?", eventID, quantity).
+		Update("available_quota", gorm.Expr("available_quota - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientTickets
+	}
+	return nil
+}
+
+// restoreEventQuota gives quantity tickets back to an event's available
+// quota, never above its total quota. It runs inside the transaction that
+// releases the tickets, so they cannot be lost between the two; call
+// notifyQuotaRestored once that transaction has committed.
+func restoreEventQuota(tx *gorm.DB, eventID uuid.UUID, quantity int) error {
+	var event models.Event
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&event, "id = ?", eventID).Error; err != nil {
+		return err
+	}
+
+	// Cancelling an event closes its quota for good.
+	if event.Status == "cancelled" {
+		return nil
+	}
+
+	return tx.Model(&models.Event{}).
+		Where("id = ?", eventID).
+		Update("available_quota", gorm.Expr("LEAST(quota, available_quota + ?)", quantity)).Error
+}
+
+func (es *EventService) UpdateEvent(id uuid.UUID, req *UpdateEventRequest) (*models.Event, error) {
+	var event models.Event
+	quotaIncreased := false
+
+	err := es.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&event, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		if event.Status == "cancelled" {
+			return invalid("cancelled events cannot be updated")
+		}
+
+		if req.Name != nil {
+			if *req.Name == "" {
+				return invalid("name cannot be empty")
+			}
+			event.Name = *req.Name
+		}
+		if req.Description != nil {
+			event.Description = *req.Description
+		}
+		if req.Location != nil {
+			if *req.Location == "" {
+				return invalid("location cannot be empty")
+			}
+			event.Location = *req.Location
+		}
+		if req.Organizer != nil {
+			event.Organizer = *req.Organizer
+		}
+		if req.Schedule != nil {
+			event.Schedule = *req.Schedule
+		}
+		if req.PriceIDR != nil {
+			if *req.PriceIDR <= 0 {
+				return invalid("price must be greater than 0")
+			}
+			event.PriceIDR = *req.PriceIDR
+		}
+		if req.Quota != nil && event.HasSeating {
+			return invalid("quota of a seated event is defined by its seating layout")
+		}
+		if req.Quota != nil {
+			sold := event.Quota - event.AvailableQuota
+			if *req.Quota < sold {
+				return invalid("quota cannot be lower than the number of tickets already sold")
+			}
+			if *req.Quota <= 0 {
+				return invalid("quota must be greater than 0")
+			}
+			quotaIncreased = *req.Quota > event.Quota
+			event.AvailableQuota = *req.Quota - sold
+			event.Quota = *req.Quota
+		}
+		if req.ClearSaleStartAt {
+			event.SaleStartAt = nil
+		} else if req.SaleStartAt != nil {
+			event.SaleStartAt = req.SaleStartAt
+		}
+		if req.ClearSaleEndAt {
+			event.SaleEndAt = nil
+		} else if req.SaleEndAt != nil {
+			event.SaleEndAt = req.SaleEndAt
+		}
+		if req.MaxTicketsPerTransaction != nil {
+			event.MaxTicketsPerTransaction = *req.MaxTicketsPerTransaction
+		}
+		if req.MaxTicketsPerCustomer != nil {
+			event.MaxTicketsPerCustomer = *req.MaxTicketsPerCustomer
+		}
+
+		if err := validateBookingRules(&event); err != nil {
+			return err
+		}
+
+		return tx.Save(&event).Error
+	})
+	if err != nil {
 		return nil, err
 	}
-	return events, nil
+
+	if quotaIncreased {
+		es.notifyQuotaRestored(id)
+	}
+
+	return &event, nil
 }
 
-func (es *EventService) UpdateEventQuota(eventID uuid.UUID, quantity int) error {
+func (es *EventService) DeleteEvent(id uuid.UUID) error {
 	return es.db.Transaction(func(tx *gorm.DB) error {
 		var event models.Event
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").
-			First(&event, "id = ?", eventID).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&event, "id = ?", id).Error; err != nil {
 			return err
 		}
 
-		if event.AvailableQuota < quantity {
-			return errors.New("insufficient tickets available")
+		var activeTransactions int64
+		if err := tx.Model(&models.Transaction{}).
+			Where("event_id = ? AND status IN ?", id, []string{"pending", "paid"}).
+			Count(&activeTransactions).Error; err != nil {
+			return err
 		}
 
-		event.AvailableQuota -= quantity
-		return tx.Save(&event).Error
+		if event.AvailableQuota != event.Quota || activeTransactions > 0 {
+			return invalid("cannot delete an event with sold tickets")
+		}
+
+		return tx.Delete(&event).Error
 	})
 }
 
-func (es *EventService) RestoreEventQuota(eventID uuid.UUID, quantity int) error {
-	return es.db.Transaction(func(tx *gorm.DB) error {
+func (es *EventService) CancelEvent(id uuid.UUID, reason string) (*EventCancellation, error) {
+	result := &EventCancellation{Refunds: []models.Refund{}}
+
+	err := es.db.Transaction(func(tx *gorm.DB) error {
 		var event models.Event
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").
-			First(&event, "id = ?", eventID).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&event, "id = ?", id).Error; err != nil {
 			return err
 		}
 
-		event.AvailableQuota += quantity
-		if event.AvailableQuota > event.Quota {
-			event.AvailableQuota = event.Quota
+		if event.Status == "cancelled" {
+			return invalid("event is already cancelled")
 		}
 
-		return tx.Save(&event).Error
+		now := time.Now()
+		event.Status = "cancelled"
+		event.CancelReason = reason
+		event.CancelledAt = &now
+		event.AvailableQuota = 0
+		if err := tx.Save(&event).Error; err != nil {
+			return err
+		}
+
+		var cancelled, refunded []uuid.UUID
+		var transactions []models.Transaction
+		if err := tx.Preload("BlockchainTransactions").
+			Where("event_id = ? AND status IN ?", id, []string{"pending", "paid"}).
+			Find(&transactions).Error; err != nil {
+			return err
+		}
+
+		for _, transaction := range transactions {
+			newStatus := "cancelled"
+			if transaction.Status == "paid" {
+				newStatus = "refund_pending"
+
+				refund := models.Refund{
+					TransactionID: transaction.ID,
+					EventID:       transaction.EventID,
+					CustomerID:    transaction.CustomerID,
+					USDTAmount:    transaction.USDTAmount,
+					ChainID:       transaction.ChainID,
+					Token:         transaction.PaymentToken,
+					TokenAmount:   transaction.TokenAmount,
+					TokenDecimals: transaction.TokenDecimals,
+					Reason:        "event cancelled",
+					Status:        "pending",
+				}
+				for _, btx := range transaction.BlockchainTransactions {
+					if btx.FromAddress != "" {
+						refund.ToAddress = btx.FromAddress
+						break
+					}
+				}
+				if err := tx.Create(&refund).Error; err != nil {
+					return err
+				}
+				result.Refunds = append(result.Refunds, refund)
+			}
+
+			if err := tx.Model(&models.Transaction{}).
+				Where("id = ?", transaction.ID).
+				Update("status", newStatus).Error; err != nil {
+				return err
+			}
+			if newStatus == "cancelled" {
+				if err := releaseSeats(tx, transaction.ID); err != nil {
+					return err
+				}
+				if err := releasePromoRedemption(tx, transaction.ID); err != nil {
+					return err
+				}
+				cancelled = append(cancelled, transaction.ID)
+			} else {
+				refunded = append(refunded, transaction.ID)
+			}
+			result.CancelledTransactions++
+		}
+
+		if err := tx.Model(&models.Ticket{}).
+			Where("event_id = ? AND status = ?", id, "active").
+			Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.WaitlistEntry{}).
+			Where("event_id = ? AND status IN ?", id, []string{"waiting", "offered"}).
+			Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+
+		for _, transactionID := range cancelled {
+			if err := recordTransactionEvent(tx, TransactionCancelled, transactionID); err != nil {
+				return err
+			}
+			if err := notifyPaymentStatus(tx, PaymentStatusUpdate{
+				Type:          PaymentCancelled,
+				TransactionID: transactionID,
+				Status:        "cancelled",
+			}); err != nil {
+				return err
+			}
+		}
+		for _, transactionID := range refunded {
+			if err := recordTransactionEvent(tx, TransactionRefundPending, transactionID); err != nil {
+				return err
+			}
+		}
+
+		result.Event = &event
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (es *EventService) CheckSalesWindow(event *models.Event, now time.Time) error {
+	if event.Status == "cancelled" {
+		return ErrEventCancelled
+	}
+
+	if event.SaleStartAt != nil && now.Before(*event.SaleStartAt) {
+		return ErrSalesNotStarted
+	}
+
+	saleEnd := event.Schedule
+	if event.SaleEndAt != nil {
+		saleEnd = *event.SaleEndAt
+	}
+	if !now.Before(saleEnd) {
+		return ErrSalesEnded
+	}
+
+	return nil
+}
+
+func validateBookingRules(event *models.Event) error {
+	if event.SaleStartAt != nil && event.SaleEndAt != nil && !event.SaleStartAt.Before(*event.SaleEndAt) {
+		return invalid("sale start must be before sale end")
+	}
+
+	if event.MaxTicketsPerTransaction < 0 || event.MaxTicketsPerCustomer < 0 {
+		return invalid("ticket limits cannot be negative")
+	}
+
+	if event.MaxTicketsPerTransaction > 0 && event.MaxTicketsPerCustomer > 0 &&
+		event.MaxTicketsPerTransaction > event.MaxTicketsPerCustomer {
+		return invalid("max tickets per transaction cannot exceed max tickets per customer")
+	}
+
+	return nil
+}
+
+const eventSearchVector = "to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(description, ''))"
+
+type eventSort struct {
+	column string
+	desc   bool
+	format func(event *models.Event) string
+	parse  func(value string) (interface{}, error)
+}
+
+var (
+	sortBySchedule = eventSort{
+		column: "schedule",
+		format: func(e *models.Event) string { return e.Schedule.Format(time.RFC3339Nano) },
+		parse:  func(v string) (interface{}, error) { return time.Parse(time.RFC3339Nano, v) },
+	}
+	sortByPrice = eventSort{
+		column: "price_idr",
+		format: func(e *models.Event) string { return strconv.FormatInt(int64(e.PriceIDR), 10) },
+		parse:  func(v string) (interface{}, error) { return strconv.ParseInt(v, 10, 64) },
+	}
+	sortByCreatedAt = eventSort{
+		column: "created_at",
+		format: func(e *models.Event) string { return e.CreatedAt.Format(time.RFC3339Nano) },
+		parse:  func(v string) (interface{}, error) { return time.Parse(time.RFC3339Nano, v) },
+	}
+)
+
+var eventSorts = map[string]eventSort{
+	"schedule":    sortBySchedule,
+	"-schedule":   descending(sortBySchedule),
+	"price":       sortByPrice,
+	"-price":      descending(sortByPrice),
+	"created_at":  sortByCreatedAt,
+	"-created_at": descending(sortByCreatedAt),
+}
+
+func descending(sort eventSort) eventSort {
+	sort.desc = true
+	return sort
+}
+
+type eventCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func encodeEventCursor(cursor eventCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeEventCursor(value string) (*eventCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalid("invalid cursor")
+	}
+
+	var cursor eventCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, invalid("invalid cursor")
+	}
+	return &cursor, nil
 }
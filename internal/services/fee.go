@@ -0,0 +1,288 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	FeeScopeGlobal    = "global"
+	FeeScopeOrganizer = "organizer"
+	FeeScopeEvent     = "event"
+)
+
+// FeeBreakdown is the fee charged on one booking and the rule it came from.
+type FeeBreakdown struct {
+	BaseIDR       money.IDR
+	FeeIDR        money.IDR
+	NetworkFeeIDR money.IDR
+	RuleID        *uuid.UUID
+	Rule          string
+	PercentBps    int64
+}
+
+// ChargedIDR is the amount the customer pays, before conversion to tokens.
+func (f *FeeBreakdown) ChargedIDR() money.IDR {
+	return f.BaseIDR + f.FeeIDR + f.NetworkFeeIDR
+}
+
+// FeeService picks the fee rule for a booking: an active rule for the event
+// wins over one for the event's organizer, which wins over a global rule.
+// Without any active rule the configured PLATFORM_FEE_PERCENT applies.
+type FeeService struct {
+	db         *gorm.DB
+	chains     *ChainRegistry
+	defaultBps int64
+}
+
+type CreateFeeRuleRequest struct {
+	Name                  string     `json:"name"`
+	Scope                 string     `json:"scope"`
+	EventID               *uuid.UUID `json:"event_id,omitempty"`
+	Organizer             string     `json:"organizer,omitempty"`
+	PercentBps            int64      `json:"percent_bps"`
+	FixedIDR              money.IDR  `json:"fixed_idr"`
+	MinIDR                money.IDR  `json:"min_idr"`
+	MaxIDR                money.IDR  `json:"max_idr"`
+	PassThroughNetworkFee bool       `json:"pass_through_network_fee"`
+}
+
+type UpdateFeeRuleRequest struct {
+	Name                  *string    `json:"name,omitempty"`
+	PercentBps            *int64     `json:"percent_bps,omitempty"`
+	FixedIDR              *money.IDR `json:"fixed_idr,omitempty"`
+	MinIDR                *money.IDR `json:"min_idr,omitempty"`
+	MaxIDR                *money.IDR `json:"max_idr,omitempty"`
+	PassThroughNetworkFee *bool      `json:"pass_through_network_fee,omitempty"`
+	Status                *string    `json:"status,omitempty"`
+}
+
+func NewFeeService(db *gorm.DB, chains *ChainRegistry, defaultPercent float64) *FeeService {
+	return &FeeService{
+		db:         db,
+		chains:     chains,
+		defaultBps: money.BasisPoints(defaultPercent),
+	}
+}
+
+// Calculate works out the fee on baseIDR for a booking of the event paid on
+// the given chain.
+func (fs *FeeService) Calculate(event *models.Event, chainID int64, baseIDR money.IDR) (*FeeBreakdown, error) {
+	rule, err := fs.ruleFor(event)
+	if err != nil {
+		return nil, err
+	}
+
+	var networkFee money.IDR
+	if rule.PassThroughNetworkFee {
+		chain, err := fs.chains.Chain(chainID)
+		if err != nil {
+			return nil, err
+		}
+		networkFee = chain.NetworkFeeIDR
+	}
+
+	breakdown := &FeeBreakdown{
+		BaseIDR:       baseIDR,
+		FeeIDR:        applyFeeRule(rule, baseIDR),
+		NetworkFeeIDR: networkFee,
+		Rule:          describeFeeRule(rule),
+		PercentBps:    rule.PercentBps,
+	}
+	if rule.ID != uuid.Nil {
+		id := rule.ID
+		breakdown.RuleID = &id
+	}
+
+	return breakdown, nil
+}
+
+func (fs *FeeService) ruleFor(event *models.Event) (*models.FeeRule, error) {
+	var rules []models.FeeRule
+	if err := fs.db.
+		Where("status = ?", "active").
+		Where("(scope = ? AND event_id = ?) OR (scope = ? AND organizer = ? AND organizer <> '') OR scope = ?",
+			FeeScopeEvent, event.ID, FeeScopeOrganizer, event.Organizer, FeeScopeGlobal).
+		Order("updated_at DESC").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	for _, scope := range []string{FeeScopeEvent, FeeScopeOrganizer, FeeScopeGlobal} {
+		for i := range rules {
+			if rules[i].Scope == scope {
+				return &rules[i], nil
+			}
+		}
+	}
+
+	return &models.FeeRule{Name: "default", Scope: FeeScopeGlobal, PercentBps: fs.defaultBps}, nil
+}
+
+// applyFeeRule charges the percentage plus the fixed fee, clamped to the
+// rule's minimum and maximum. A maximum of zero means no cap.
+func applyFeeRule(rule *models.FeeRule, baseIDR money.IDR) money.IDR {
+	fee := baseIDR.MulBasisPoints(rule.PercentBps) + rule.FixedIDR
+	if fee < rule.MinIDR {
+		fee = rule.MinIDR
+	}
+	if rule.MaxIDR > 0 && fee > rule.MaxIDR {
+		fee = rule.MaxIDR
+	}
+	return fee
+}
+
+// describeFeeRule summarises a rule for the booking record, e.g.
+// "Jakarta shows (organizer): 2.5% + Rp 2000, max Rp 50000 + network fee".
+func describeFeeRule(rule *models.FeeRule) string {
+	var parts []string
+	if rule.PercentBps > 0 || rule.FixedIDR == 0 {
+		parts = append(parts, money.UnitsFromInt64(rule.PercentBps).Format(2)+"%")
+	}
+	if rule.FixedIDR > 0 {
+		parts = append(parts, fmt.Sprintf("Rp %d", rule.FixedIDR))
+	}
+	description := fmt.Sprintf("%s (%s): %s", rule.Name, rule.Scope, strings.Join(parts, " + "))
+
+	if rule.MinIDR > 0 {
+		description += fmt.Sprintf(", min Rp %d", rule.MinIDR)
+	}
+	if rule.MaxIDR > 0 {
+		description += fmt.Sprintf(", max Rp %d", rule.MaxIDR)
+	}
+	if rule.PassThroughNetworkFee {
+		description += " + network fee"
+	}
+	return description
+}
+
+func (fs *FeeService) ListFeeRules() ([]models.FeeRule, error) {
+	var rules []models.FeeRule
+	if err := fs.db.Order("created_at DESC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (fs *FeeService) CreateFeeRule(req *CreateFeeRuleRequest) (*models.FeeRule, error) {
+	rule := &models.FeeRule{
+		Name:                  strings.TrimSpace(req.Name),
+		Scope:                 strings.ToLower(strings.TrimSpace(req.Scope)),
+		PercentBps:            req.PercentBps,
+		FixedIDR:              req.FixedIDR,
+		MinIDR:                req.MinIDR,
+		MaxIDR:                req.MaxIDR,
+		PassThroughNetworkFee: req.PassThroughNetworkFee,
+		Status:                "active",
+	}
+
+	switch rule.Scope {
+	case FeeScopeGlobal:
+	case FeeScopeOrganizer:
+		rule.Organizer = strings.TrimSpace(req.Organizer)
+		if rule.Organizer == "" {
+			return nil, errors.New("organizer is required for organizer fee rules")
+		}
+	case FeeScopeEvent:
+		if req.EventID == nil {
+			return nil, errors.New("event_id is required for event fee rules")
+		}
+		var count int64
+		if err := fs.db.Model(&models.Event{}).Where("id = ?", *req.EventID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrEventNotFound
+		}
+		rule.EventID = req.EventID
+	default:
+		return nil, fmt.Errorf("scope must be one of %s, %s or %s", FeeScopeGlobal, FeeScopeOrganizer, FeeScopeEvent)
+	}
+
+	if err := validateFeeRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := fs.db.Create(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (fs *FeeService) UpdateFeeRule(id uuid.UUID, req *UpdateFeeRuleRequest) (*models.FeeRule, error) {
+	var rule models.FeeRule
+	if err := fs.db.First(&rule, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		rule.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.PercentBps != nil {
+		rule.PercentBps = *req.PercentBps
+	}
+	if req.FixedIDR != nil {
+		rule.FixedIDR = *req.FixedIDR
+	}
+	if req.MinIDR != nil {
+		rule.MinIDR = *req.MinIDR
+	}
+	if req.MaxIDR != nil {
+		rule.MaxIDR = *req.MaxIDR
+	}
+	if req.PassThroughNetworkFee != nil {
+		rule.PassThroughNetworkFee = *req.PassThroughNetworkFee
+	}
+	if req.Status != nil {
+		if *req.Status != "active" && *req.Status != "disabled" {
+			return nil, errors.New("status must be active or disabled")
+		}
+		rule.Status = *req.Status
+	}
+
+	if err := validateFeeRule(&rule); err != nil {
+		return nil, err
+	}
+
+	if err := fs.db.Save(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DisableFeeRule stops a rule from applying to new bookings. Rules are never
+// deleted because bookings keep a reference to the rule they were priced by.
+func (fs *FeeService) DisableFeeRule(id uuid.UUID) error {
+	result := fs.db.Model(&models.FeeRule{}).
+		Where("id = ?", id).
+		Update("status", "disabled")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func validateFeeRule(rule *models.FeeRule) error {
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+	if rule.PercentBps < 0 || rule.PercentBps > 10000 {
+		return errors.New("percent_bps must be between 0 and 10000")
+	}
+	if rule.FixedIDR < 0 || rule.MinIDR < 0 || rule.MaxIDR < 0 {
+		return errors.New("fee amounts cannot be negative")
+	}
+	if rule.MaxIDR > 0 && rule.MaxIDR < rule.MinIDR {
+		return errors.New("max_idr cannot be below min_idr")
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
+	"testing"
+)
+
+func TestApplyFeeRule(t *testing.T) {
+	tests := []struct {
+		name string
+		rule models.FeeRule
+		base money.IDR
+		want money.IDR
+	}{
+		{name: "percent", rule: models.FeeRule{PercentBps: 250}, base: 100000, want: 2500},
+		{name: "percent rounds half up", rule: models.FeeRule{PercentBps: 250}, base: 1020, want: 26},
+		{name: "percent plus fixed", rule: models.FeeRule{PercentBps: 250, FixedIDR: 2000}, base: 100000, want: 4500},
+		{name: "fixed only", rule: models.FeeRule{FixedIDR: 5000}, base: 100000, want: 5000},
+		{name: "raised to minimum", rule: models.FeeRule{PercentBps: 100, MinIDR: 3000}, base: 100000, want: 3000},
+		{name: "capped at maximum", rule: models.FeeRule{PercentBps: 1000, MaxIDR: 50000}, base: 1000000, want: 50000},
+		{name: "zero maximum is no cap", rule: models.FeeRule{PercentBps: 1000}, base: 1000000, want: 100000},
+		{name: "free", rule: models.FeeRule{}, base: 100000, want: 0},
+		{name: "zero base", rule: models.FeeRule{PercentBps: 250, MinIDR: 1000}, base: 0, want: 1000},
+	}
+
+	for _, tt := range tests {
+		if got := applyFeeRule(&tt.rule, tt.base); got != tt.want {
+			t.Errorf("%s: applyFeeRule = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeFeeRule(t *testing.T) {
+	tests := []struct {
+		rule models.FeeRule
+		want string
+	}{
+		{
+			rule: models.FeeRule{Name: "default", Scope: FeeScopeGlobal, PercentBps: 250},
+			want: "default (global): 2.5%",
+		},
+		{
+			rule: models.FeeRule{Name: "Jakarta shows", Scope: FeeScopeOrganizer, PercentBps: 250, FixedIDR: 2000, MaxIDR: 50000, PassThroughNetworkFee: true},
+			want: "Jakarta shows (organizer): 2.5% + Rp 2000, max Rp 50000 + network fee",
+		},
+		{
+			rule: models.FeeRule{Name: "flat", Scope: FeeScopeEvent, FixedIDR: 5000, MinIDR: 5000},
+			want: "flat (event): Rp 5000, min Rp 5000",
+		},
+		{
+			rule: models.FeeRule{Name: "free", Scope: FeeScopeEvent},
+			want: "free (event): 0%",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := describeFeeRule(&tt.rule); got != tt.want {
+			t.Errorf("describeFeeRule = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestValidateFeeRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    models.FeeRule
+		wantErr bool
+	}{
+		{name: "valid", rule: models.FeeRule{Name: "r", PercentBps: 250, MinIDR: 1000, MaxIDR: 5000}},
+		{name: "full percent", rule: models.FeeRule{Name: "r", PercentBps: 10000}},
+		{name: "no name", rule: models.FeeRule{PercentBps: 250}, wantErr: true},
+		{name: "percent too high", rule: models.FeeRule{Name: "r", PercentBps: 10001}, wantErr: true},
+		{name: "negative percent", rule: models.FeeRule{Name: "r", PercentBps: -1}, wantErr: true},
+		{name: "negative fixed", rule: models.FeeRule{Name: "r", FixedIDR: -1}, wantErr: true},
+		{name: "max below min", rule: models.FeeRule{Name: "r", MinIDR: 5000, MaxIDR: 1000}, wantErr: true},
+		{name: "min without max", rule: models.FeeRule{Name: "r", MinIDR: 5000}},
+	}
+
+	for _, tt := range tests {
+		if err := validateFeeRule(&tt.rule); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateFeeRule error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestChargedIDR(t *testing.T) {
+	fee := FeeBreakdown{BaseIDR: 100000, FeeIDR: 2500, NetworkFeeIDR: 300}
+	if got := fee.ChargedIDR(); got != 102800 {
+		t.Errorf("ChargedIDR = %d, want 102800", got)
+	}
+}
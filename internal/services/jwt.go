@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtLeeway allows for clock skew between the token issuer and this server.
+const jwtLeeway = 30 * time.Second
+
+// jwtClaims are the claims read from and written to user tokens.
+type jwtClaims struct {
+	Role      string `json:"role"`
+	Email     string `json:"email,omitempty"`
+	Organizer string `json:"organizer,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (c *jwtClaims) hasAudience(audience string) bool {
+	return slices.Contains(c.Audience, audience)
+}
+
+func signJWT(secret []byte, claims *jwtClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// parseJWT verifies an HS256 token and returns its claims. Tokens signed
+// with any other algorithm, including "none", are rejected, as are tokens
+// without an expiry.
+func parseJWT(secret []byte, token string, now time.Time) (*jwtClaims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+
+	var claims jwtClaims
+	_, err := parser.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	})
+	switch {
+	case err == nil:
+		return &claims, nil
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return nil, invalidToken("token is malformed")
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return nil, invalidToken("token signature is invalid")
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return nil, invalidToken("token has no expiry")
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, invalidToken("token has expired")
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return nil, invalidToken("token is not valid yet")
+	default:
+		return nil, invalidToken("token is invalid")
+	}
+}
+
+func invalidToken(message string) *AuthError {
+	return &AuthError{Code: "INVALID_TOKEN", Message: message}
+}
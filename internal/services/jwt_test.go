@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testJWTSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func testToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign test token: %v", err)
+	}
+	return token
+}
+
+func TestParseJWT(t *testing.T) {
+	now := time.Date(2025, 7, 30, 12, 0, 0, 0, time.UTC)
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{"sub": "user-1", "role": RoleAdmin, "exp": now.Add(time.Hour).Unix()}
+	}
+	with := func(key string, value interface{}) jwt.MapClaims {
+		claims := valid()
+		claims[key] = value
+		return claims
+	}
+	without := func(key string) jwt.MapClaims {
+		claims := valid()
+		delete(claims, key)
+		return claims
+	}
+	signed := testToken(t, jwt.SigningMethodHS256, testJWTSecret, valid())
+	parts := strings.Split(signed, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "valid", token: signed},
+		{name: "fractional exp", token: testToken(t, jwt.SigningMethodHS256, testJWTSecret, with("exp", float64(now.Add(time.Hour).Unix())+0.5))},
+		{name: "alg none", token: testToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid()), wantErr: "token signature is invalid"},
+		{name: "alg none with empty signature", token: "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." + parts[1] + ".", wantErr: "token signature is invalid"},
+		{name: "HS384", token: testToken(t, jwt.SigningMethodHS384, testJWTSecret, valid()), wantErr: "token signature is invalid"},
+		{name: "HS512", token: testToken(t, jwt.SigningMethodHS512, testJWTSecret, valid()), wantErr: "token signature is invalid"},
+		{name: "other secret", token: testToken(t, jwt.SigningMethodHS256, []byte("fedcba9876543210fedcba9876543210"), valid()), wantErr: "token signature is invalid"},
+		{name: "tampered claims", token: parts[0] + "." + strings.Split(testToken(t, jwt.SigningMethodHS256, testJWTSecret, with("role", RoleCustomer)), ".")[1] + "." + parts[2], wantErr: "token signature is invalid"},
+		{name: "missing signature", token: parts[0] + "." + parts[1], wantErr: "token is malformed"},
+		{name: "garbage", token: "not-a-token", wantErr: "token is malformed"},
+		{name: "no expiry", token: testToken(t, jwt.SigningMethodHS256, testJWTSecret, without("exp")), wantErr: "token has no expiry"},
+		{name: "expired within leeway", token: testToken(t, jwt.SigningMethodHS256, testJWTSecret, with("exp", now.Add(-jwtLeeway/2).Unix()))},
+		{name: "expired beyond leeway", token: testToken(t, jwt.SigningMethodHS256, testJWTSecret, with("exp", now.Add(-2*jwtLeeway).Unix())), wantErr: "token has expired"},
+		{name: "not before within leeway", token: testToken(t, jwt.SigningMethodHS256, testJWTSecret, with("nbf", now.Add(jwtLeeway/2).Unix()))},
+		{name: "not before beyond leeway", token: testToken(t, jwt.SigningMethodHS256, testJWTSecret, with("nbf", now.Add(2*jwtLeeway).Unix())), wantErr: "token is not valid yet"},
+		{name: "exp of wrong type", token: testToken(t, jwt.SigningMethodHS256, testJWTSecret, with("exp", "tomorrow")), wantErr: "token is malformed"},
+	}
+
+	for _, tt := range tests {
+		claims, err := parseJWT(testJWTSecret, tt.token, now)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: parseJWT returned error: %v", tt.name, err)
+			} else if claims.Subject != "user-1" {
+				t.Errorf("%s: subject = %q, want user-1", tt.name, claims.Subject)
+			}
+			continue
+		}
+		var authErr *AuthError
+		if !errors.As(err, &authErr) || authErr.Message != tt.wantErr {
+			t.Errorf("%s: parseJWT error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSignJWTRoundTrip(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	token, err := signJWT(testJWTSecret, &jwtClaims{
+		Role:  RoleCustomer,
+		Email: "buyer@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			Audience:  jwt.ClaimStrings{"tickets"},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := parseJWT(testJWTSecret, token, time.Now())
+	if err != nil {
+		t.Fatalf("parseJWT returned error: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Role != RoleCustomer || claims.Email != "buyer@example.com" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if !claims.ExpiresAt.Time.Equal(expiresAt) {
+		t.Errorf("exp = %v, want %v", claims.ExpiresAt.Time, expiresAt)
+	}
+	if !claims.hasAudience("tickets") {
+		t.Errorf("aud = %v, want tickets", claims.Audience)
+	}
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	service, err := NewAuthService(nil, "", string(testJWTSecret), "sermorpheus", "tickets", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	token := func(iss string, aud interface{}) string {
+		claims := jwt.MapClaims{"sub": "user-1", "role": RoleAdmin, "exp": exp}
+		if iss != "" {
+			claims["iss"] = iss
+		}
+		if aud != nil {
+			claims["aud"] = aud
+		}
+		return testToken(t, jwt.SigningMethodHS256, testJWTSecret, claims)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "audience string", token: token("sermorpheus", "tickets")},
+		{name: "audience list", token: token("sermorpheus", []string{"billing", "tickets"})},
+		{name: "other audience", token: token("sermorpheus", "billing"), wantErr: "token audience is not accepted"},
+		{name: "other audience list", token: token("sermorpheus", []string{"billing", "reports"}), wantErr: "token audience is not accepted"},
+		{name: "no audience", token: token("sermorpheus", nil), wantErr: "token audience is not accepted"},
+		{name: "other issuer", token: token("someone-else", "tickets"), wantErr: "token issuer is not accepted"},
+		{name: "no issuer", token: token("", "tickets"), wantErr: "token issuer is not accepted"},
+	}
+
+	for _, tt := range tests {
+		principal, err := service.AuthenticateToken(tt.token)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: AuthenticateToken returned error: %v", tt.name, err)
+			} else if principal.Subject != "user-1" || principal.Method != AuthMethodJWT {
+				t.Errorf("%s: principal = %+v", tt.name, principal)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("%s: AuthenticateToken error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAuthenticateAdminKey(t *testing.T) {
+	service, err := NewAuthService(nil, "admin-secret", "", "", "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, err := service.AuthenticateAdminKey("admin-secret", "alice")
+	if err != nil {
+		t.Fatalf("AuthenticateAdminKey returned error: %v", err)
+	}
+	if principal.Subject != "admin" || principal.ClaimedBy != "alice" || principal.Role != RoleAdmin {
+		t.Errorf("principal = %+v, want subject admin claimed by alice", principal)
+	}
+
+	if _, err := service.AuthenticateAdminKey("wrong", "alice"); err == nil {
+		t.Error("AuthenticateAdminKey accepted the wrong key")
+	}
+}
@@ -0,0 +1,499 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kinds of customer email.
+const (
+	EmailPaymentInstructions = "payment_instructions"
+	EmailPaymentReceived     = "payment_received"
+	EmailExpiryWarning       = "expiry_warning"
+	EmailRefund              = "refund"
+	EmailWaitlistOffer       = "waitlist_offer"
+)
+
+var EmailKinds = []string{
+	EmailPaymentInstructions,
+	EmailPaymentReceived,
+	EmailExpiryWarning,
+	EmailRefund,
+	EmailWaitlistOffer,
+}
+
+const (
+	emailBatchSize    = 20
+	emailLease        = 5 * time.Minute
+	emailMaxBackoff   = 6 * time.Hour
+	emailDateLayout   = "02 Jan 2006 15:04 MST"
+	ticketContentType = "text/html"
+)
+
+var ErrEmailAlreadySent = errors.New("email has already been sent")
+
+// NotificationService emails customers about their orders. Emails are
+// rendered when they are queued, stored in email_messages and sent by a
+// background worker that retries failures with exponential backoff. Every
+// email has a dedupe key, so queueing the same email twice sends it once.
+type NotificationService struct {
+	db            *gorm.DB
+	chains        *ChainRegistry
+	sender        *SMTPSender
+	templates     *emailTemplates
+	defaultLocale string
+	location      *time.Location
+	maxAttempts   int
+	retryBase     time.Duration
+}
+
+func NewNotificationService(db *gorm.DB, chains *ChainRegistry, sender *SMTPSender, defaultLocale, timezone string, maxAttempts int, retryBase time.Duration) (*NotificationService, error) {
+	locale := normalizeLocale(defaultLocale)
+	if locale == "" {
+		return nil, fmt.Errorf("EMAIL_DEFAULT_LOCALE %q is not supported", defaultLocale)
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("EMAIL_TIMEZONE: %w", err)
+	}
+	templates, err := loadEmailTemplates(EmailKinds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotificationService{
+		db:            db,
+		chains:        chains,
+		sender:        sender,
+		templates:     templates,
+		defaultLocale: locale,
+		location:      location,
+		maxAttempts:   max(maxAttempts, 1),
+		retryBase:     retryBase,
+	}, nil
+}
+
+// QueueForEvent queues the email a transaction lifecycle event calls for,
+// if any. It is keyed on the event ID, so the outbox relay can retry it.
+func (ns *NotificationService) QueueForEvent(event *models.OutboxEvent) error {
+	var kind string
+	switch event.EventType {
+	case TransactionCreated:
+		kind = EmailPaymentInstructions
+	case TransactionPaid:
+		kind = EmailPaymentReceived
+	case TransactionRefunded:
+		kind = EmailRefund
+	case TransactionPaymentReminder:
+		kind = EmailExpiryWarning
+	case WaitlistOffered:
+		return ns.queueWaitlistOffer(event)
+	default:
+		return nil
+	}
+
+	transaction, err := ns.loadTransaction(event.AggregateID)
+	if err != nil {
+		return err
+	}
+	// Instructions and reminders relayed late are pointless once the order
+	// is settled.
+	if (kind == EmailPaymentInstructions || kind == EmailExpiryWarning) && transaction.Status != "pending" {
+		return nil
+	}
+
+	return ns.queue(kind+":"+event.ID.String(), kind, transaction)
+}
+
+// queueWaitlistOffer tells a waitlisted customer that tickets are held for
+// them, unless the offer is no longer open by the time the event is relayed.
+func (ns *NotificationService) queueWaitlistOffer(event *models.OutboxEvent) error {
+	var entry models.WaitlistEntry
+	if err := ns.db.Preload("Customer").Preload("Event").
+		First(&entry, "id = ?", event.AggregateID).Error; err != nil {
+		return err
+	}
+	if entry.Status != "offered" || entry.OfferExpiresAt == nil || entry.OfferExpiresAt.Before(time.Now()) {
+		return nil
+	}
+
+	customer := entry.Customer
+	locale := customer.Locale
+	if locale == "" {
+		locale = ns.defaultLocale
+	}
+
+	data := emailData{
+		Name:    customer.Name,
+		EntryID: entry.ID.String(),
+		Event: emailEvent{
+			Name:     entry.Event.Name,
+			Date:     entry.Event.Schedule.In(ns.location).Format(emailDateLayout),
+			Location: entry.Event.Location,
+		},
+		Quantity: entry.Quantity,
+		Total:    formatRupiah(entry.Event.PriceIDR * money.IDR(entry.Quantity)),
+		Deadline: entry.OfferExpiresAt.In(ns.location).Format(emailDateLayout),
+	}
+	subject, text, html, err := ns.templates.render(EmailWaitlistOffer, locale, data)
+	if err != nil {
+		return fmt.Errorf("failed to render %s email: %w", EmailWaitlistOffer, err)
+	}
+
+	message := &models.EmailMessage{
+		DedupeKey:     EmailWaitlistOffer + ":" + event.ID.String(),
+		Kind:          EmailWaitlistOffer,
+		CustomerID:    &customer.ID,
+		ToAddress:     customer.Email,
+		ToName:        customer.Name,
+		Locale:        locale,
+		Subject:       subject,
+		TextBody:      text,
+		HTMLBody:      html,
+		Status:        "pending",
+		NextAttemptAt: time.Now(),
+		Attachments:   []models.EmailAttachment{},
+	}
+	return ns.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "dedupe_key"}},
+		DoNothing: true,
+	}).Create(message).Error
+}
+
+func (ns *NotificationService) loadTransaction(id uuid.UUID) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := ns.db.Preload("Customer").
+		Preload("Event").
+		Preload("Tickets", func(db *gorm.DB) *gorm.DB { return db.Order("ticket_code") }).
+		First(&transaction, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func (ns *NotificationService) queue(dedupeKey, kind string, transaction *models.Transaction) error {
+	message, err := ns.compose(kind, transaction)
+	if err != nil {
+		return fmt.Errorf("failed to render %s email: %w", kind, err)
+	}
+	message.DedupeKey = dedupeKey
+
+	return ns.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "dedupe_key"}},
+		DoNothing: true,
+	}).Create(message).Error
+}
+
+type emailEvent struct {
+	Name     string
+	Date     string
+	Location string
+}
+
+type emailTicket struct {
+	Code    string
+	Seat    string
+	Holder  string
+	OrderID string
+	Event   emailEvent
+}
+
+type emailRefund struct {
+	Amount    string
+	ToAddress string
+	Reason    string
+}
+
+type emailData struct {
+	Name        string
+	OrderID     string
+	EntryID     string
+	Event       emailEvent
+	Quantity    int
+	Total       string
+	Amount      string
+	Network     string
+	Address     string
+	Deadline    string
+	MinutesLeft int
+	TxHash      string
+	Tickets     []emailTicket
+	Refund      *emailRefund
+}
+
+func (ns *NotificationService) compose(kind string, transaction *models.Transaction) (*models.EmailMessage, error) {
+	customer := transaction.Customer
+	locale := customer.Locale
+	if locale == "" {
+		locale = ns.defaultLocale
+	}
+
+	data := emailData{
+		Name:    customer.Name,
+		OrderID: transaction.ID.String(),
+		Event: emailEvent{
+			Name:     transaction.Event.Name,
+			Date:     transaction.Event.Schedule.In(ns.location).Format(emailDateLayout),
+			Location: transaction.Event.Location,
+		},
+		Quantity: transaction.Quantity,
+		Total:    formatRupiah(transaction.ChargedIDR),
+		Amount:   transaction.TokenAmount.Format(transaction.TokenDecimals) + " " + transaction.PaymentToken,
+		Network:  fmt.Sprintf("chain %d", transaction.ChainID),
+		Address:  transaction.PaymentAddress,
+	}
+	if transaction.ChargedIDR == 0 {
+		data.Total = formatRupiah(transaction.TotalIDR)
+	}
+	if chain, err := ns.chains.Chain(transaction.ChainID); err == nil {
+		data.Network = chain.Name
+	}
+	if deadline := transaction.PaymentDeadline; deadline != nil {
+		data.Deadline = deadline.In(ns.location).Format(emailDateLayout)
+		data.MinutesLeft = max(int(time.Until(*deadline).Round(time.Minute)/time.Minute), 1)
+	}
+
+	message := &models.EmailMessage{
+		Kind:          kind,
+		TransactionID: &transaction.ID,
+		CustomerID:    &customer.ID,
+		ToAddress:     customer.Email,
+		ToName:        customer.Name,
+		Locale:        locale,
+		Status:        "pending",
+		NextAttemptAt: time.Now(),
+		Attachments:   []models.EmailAttachment{},
+	}
+
+	switch kind {
+	case EmailPaymentReceived:
+		var blockchainTx models.BlockchainTransaction
+		if err := ns.db.Where("transaction_id = ? AND status = ?", transaction.ID, "confirmed").
+			Order("created_at DESC").
+			First(&blockchainTx).Error; err == nil {
+			data.TxHash = blockchainTx.TxHash
+		}
+
+		for _, ticket := range transaction.Tickets {
+			if ticket.Status != "active" {
+				continue
+			}
+			eTicket := emailTicket{
+				Code:    ticket.TicketCode,
+				Seat:    ticket.Seat,
+				Holder:  customer.Name,
+				OrderID: data.OrderID,
+				Event:   data.Event,
+			}
+			content, err := ns.templates.renderTicket(locale, eTicket)
+			if err != nil {
+				return nil, err
+			}
+			data.Tickets = append(data.Tickets, eTicket)
+			message.Attachments = append(message.Attachments, models.EmailAttachment{
+				Filename:    "ticket-" + ticket.TicketCode + ".html",
+				ContentType: ticketContentType,
+				Content:     content,
+			})
+		}
+
+	case EmailRefund:
+		var refund models.Refund
+		if err := ns.db.Where("transaction_id = ?", transaction.ID).
+			Order("created_at DESC").
+			First(&refund).Error; err != nil {
+			return nil, err
+		}
+		data.Refund = &emailRefund{
+			Amount:    refund.TokenAmount.Format(refund.TokenDecimals) + " " + refund.Token,
+			ToAddress: refund.ToAddress,
+			Reason:    refund.Reason,
+		}
+		data.TxHash = refund.TxHash
+		if chain, err := ns.chains.Chain(refund.ChainID); err == nil {
+			data.Network = chain.Name
+		}
+	}
+
+	subject, text, html, err := ns.templates.render(kind, locale, data)
+	if err != nil {
+		return nil, err
+	}
+	message.Subject = subject
+	message.TextBody = text
+	message.HTMLBody = html
+	return message, nil
+}
+
+// formatRupiah formats an amount the Indonesian way, e.g. Rp 1.250.000.
+func formatRupiah(amount money.IDR) string {
+	digits := strconv.FormatInt(int64(amount), 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	var grouped strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + "Rp " + grouped.String()
+}
+
+func (ns *NotificationService) StartDeliveryWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := ns.SendDue(); err != nil {
+				log.Printf("Failed to send emails: %v", err)
+			}
+		}
+	}()
+}
+
+// SendDue sends the emails whose next attempt is due. Claimed emails are
+// leased so other replicas skip them while they are being sent.
+func (ns *NotificationService) SendDue() (int, error) {
+	var messages []models.EmailMessage
+
+	err := ns.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", "pending", now).
+			Order("next_attempt_at").
+			Limit(emailBatchSize).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(messages))
+		for i := range messages {
+			ids[i] = messages[i].ID
+		}
+		return tx.Model(&models.EmailMessage{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(emailLease)).Error
+	})
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range messages {
+		ok, err := ns.send(&messages[i])
+		if err != nil {
+			log.Printf("Failed to record email %s: %v", messages[i].ID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (ns *NotificationService) send(message *models.EmailMessage) (bool, error) {
+	sendErr := ns.sender.Send(message)
+
+	attempts := message.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts}
+	now := time.Now()
+	switch {
+	case sendErr == nil:
+		updates["status"] = "sent"
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+	case attempts >= ns.maxAttempts || isPermanentSMTPError(sendErr):
+		updates["status"] = "failed"
+		updates["last_error"] = sendErr.Error()
+		log.Printf("Email %s (%s) to %s failed after %d attempts: %v", message.ID, message.Kind, message.ToAddress, attempts, sendErr)
+	default:
+		updates["last_error"] = sendErr.Error()
+		updates["next_attempt_at"] = now.Add(ns.backoff(attempts))
+	}
+
+	if err := ns.db.Model(&models.EmailMessage{}).
+		Where("id = ?", message.ID).
+		Updates(updates).Error; err != nil {
+		return false, err
+	}
+	return sendErr == nil, nil
+}
+
+func (ns *NotificationService) backoff(attempt int) time.Duration {
+	wait := ns.retryBase
+	for i := 1; i < attempt && wait < emailMaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, emailMaxBackoff)
+}
+
+// ListMessages returns the email log, newest first, without bodies and
+// attachments.
+func (ns *NotificationService) ListMessages(status, kind string, transactionID *uuid.UUID, limit int) ([]models.EmailMessage, error) {
+	query := ns.db.Model(&models.EmailMessage{}).
+		Omit("text_body", "html_body", "attachments")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if transactionID != nil {
+		query = query.Where("transaction_id = ?", *transactionID)
+	}
+
+	var messages []models.EmailMessage
+	if err := query.Order("created_at DESC").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (ns *NotificationService) GetMessage(id uuid.UUID) (*models.EmailMessage, error) {
+	var message models.EmailMessage
+	if err := ns.db.First(&message, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// Retry sends a pending or failed email on the worker's next pass. A failed
+// email gets one more attempt.
+func (ns *NotificationService) Retry(id uuid.UUID) (*models.EmailMessage, error) {
+	message, err := ns.GetMessage(id)
+	if err != nil {
+		return nil, err
+	}
+	if message.Status == "sent" {
+		return nil, ErrEmailAlreadySent
+	}
+
+	message.Status = "pending"
+	message.NextAttemptAt = time.Now()
+	if err := ns.db.Model(message).Updates(map[string]interface{}{
+		"status":          message.Status,
+		"next_attempt_at": message.NextAttemptAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return message, nil
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sermorpheus-engine-test/internal/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	outboxBatchSize  = 100
+	outboxLease      = time.Minute
+	outboxRetryBase  = time.Second
+	outboxMaxBackoff = 5 * time.Minute
+)
+
+var ErrOutboxEventPublished = errors.New("event has already been published")
+
+// OutboxSink publishes outbox events somewhere outside the database.
+// Publish may be called more than once for the same event, so sinks and
+// their consumers should deduplicate on the event ID.
+type OutboxSink interface {
+	Name() string
+	Publish(event *models.OutboxEvent) error
+}
+
+// OutboxService relays events from the outbox table to the configured sinks.
+// An event stays pending until every sink has accepted it; sinks that
+// already did are remembered and skipped on retry. Failed events are retried
+// with backoff until they succeed, giving at-least-once delivery. Events of
+// one aggregate are published in the order they were written.
+type OutboxService struct {
+	db    *gorm.DB
+	sinks []OutboxSink
+}
+
+func NewOutboxService(db *gorm.DB, sinks []OutboxSink) *OutboxService {
+	return &OutboxService{db: db, sinks: sinks}
+}
+
+func (ob *OutboxService) StartRelayWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := ob.RelayPending(); err != nil {
+				log.Printf("Failed to relay outbox events: %v", err)
+			}
+		}
+	}()
+}
+
+// StartRetentionJob deletes published events older than retention.
+func (ob *OutboxService) StartRetentionJob(interval, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			result := ob.db.Where("status = ? AND published_at < ?", "published", time.Now().Add(-retention)).
+				Delete(&models.OutboxEvent{})
+			if result.Error != nil {
+				log.Printf("Failed to purge published outbox events: %v", result.Error)
+				continue
+			}
+			if result.RowsAffected > 0 {
+				log.Printf("Purged %d published outbox events", result.RowsAffected)
+			}
+		}
+	}()
+}
+
+// RelayPending publishes the events that are due. Only the oldest pending
+// event of each aggregate is claimed, and claimed events are leased so other
+// replicas skip them while they are in flight.
+func (ob *OutboxService) RelayPending() (int, error) {
+	var events []models.OutboxEvent
+
+	err := ob.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", "pending", now).
+			Where("NOT EXISTS (SELECT 1 FROM outbox_events earlier WHERE earlier.aggregate_id = outbox_events.aggregate_id AND earlier.status = ? AND earlier.created_at < outbox_events.created_at)", "pending").
+			Order("created_at").
+			Limit(outboxBatchSize).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(events))
+		for i := range events {
+			ids[i] = events[i].ID
+		}
+		return tx.Model(&models.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(outboxLease)).Error
+	})
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	published := 0
+	for i := range events {
+		ok, err := ob.relay(&events[i])
+		if err != nil {
+			log.Printf("Failed to record outbox event %s: %v", events[i].ID, err)
+			continue
+		}
+		if ok {
+			published++
+		}
+	}
+
+	return published, nil
+}
+
+func (ob *OutboxService) relay(event *models.OutboxEvent) (bool, error) {
+	done := make(map[string]bool)
+	for _, name := range strings.Split(event.PublishedSinks, ",") {
+		if name != "" {
+			done[name] = true
+		}
+	}
+
+	var failures []string
+	for _, sink := range ob.sinks {
+		if done[sink.Name()] {
+			continue
+		}
+		if err := sink.Publish(event); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", sink.Name(), err))
+			continue
+		}
+		done[sink.Name()] = true
+	}
+
+	var names []string
+	for _, sink := range ob.sinks {
+		if done[sink.Name()] {
+			names = append(names, sink.Name())
+		}
+	}
+
+	attempts := event.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"published_sinks": strings.Join(names, ","),
+	}
+	now := time.Now()
+	if len(failures) == 0 {
+		updates["status"] = "published"
+		updates["published_at"] = now
+		updates["last_error"] = ""
+	} else {
+		updates["last_error"] = strings.Join(failures, "; ")
+		updates["next_attempt_at"] = now.Add(outboxBackoff(attempts))
+		log.Printf("Outbox event %s (%s) not published, attempt %d: %s", event.ID, event.EventType, attempts, updates["last_error"])
+	}
+
+	if err := ob.db.Model(&models.OutboxEvent{}).
+		Where("id = ?", event.ID).
+		Updates(updates).Error; err != nil {
+		return false, err
+	}
+	return len(failures) == 0, nil
+}
+
+func outboxBackoff(attempt int) time.Duration {
+	wait := outboxRetryBase
+	for i := 1; i < attempt && wait < outboxMaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, outboxMaxBackoff)
+}
+
+func (ob *OutboxService) ListEvents(status string, limit int) ([]models.OutboxEvent, error) {
+	query := ob.db.Model(&models.OutboxEvent{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var events []models.OutboxEvent
+	if err := query.Order("created_at DESC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// RetryNow makes a pending event due immediately instead of waiting out its
+// backoff.
+func (ob *OutboxService) RetryNow(id uuid.UUID) (*models.OutboxEvent, error) {
+	var event models.OutboxEvent
+	if err := ob.db.First(&event, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if event.Status != "pending" {
+		return nil, ErrOutboxEventPublished
+	}
+
+	event.NextAttemptAt = time.Now()
+	if err := ob.db.Model(&event).Update("next_attempt_at", event.NextAttemptAt).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sermorpheus-engine-test/internal/config"
+	"sermorpheus-engine-test/internal/models"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NewOutboxSinks builds the sinks named in OUTBOX_SINKS, plus the email sink
+// when EMAIL_ENABLED is set.
+func NewOutboxSinks(cfg *config.Config, webhooks *WebhookService, notifications *NotificationService) ([]OutboxSink, error) {
+	var sinks []OutboxSink
+	seen := make(map[string]bool)
+
+	for _, name := range cfg.OutboxSinks {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		switch name {
+		case "webhook":
+			sinks = append(sinks, &WebhookSink{webhooks: webhooks})
+		case "log":
+			sinks = append(sinks, &LogSink{})
+		case "nats":
+			sink, err := NewNATSSink(cfg.NATSURL, cfg.NATSSubjectPrefix, cfg.OutboxPublishTimeout)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case "kafka":
+			sink, err := NewKafkaRESTSink(cfg.KafkaRESTURL, cfg.KafkaTopic, cfg.OutboxPublishTimeout)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+
+	if cfg.EmailEnabled {
+		sinks = append(sinks, &EmailSink{notifications: notifications})
+	}
+
+	return sinks, nil
+}
+
+// WebhookSink queues the event for every subscription interested in it.
+type WebhookSink struct {
+	webhooks *WebhookService
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+func (s *WebhookSink) Publish(event *models.OutboxEvent) error {
+	return s.webhooks.Enqueue(event)
+}
+
+// EmailSink queues the customer email an event calls for.
+type EmailSink struct {
+	notifications *NotificationService
+}
+
+func (s *EmailSink) Name() string { return "email" }
+
+func (s *EmailSink) Publish(event *models.OutboxEvent) error {
+	return s.notifications.QueueForEvent(event)
+}
+
+// LogSink writes events to the application log.
+type LogSink struct{}
+
+func (s *LogSink) Name() string { return "log" }
+
+func (s *LogSink) Publish(event *models.OutboxEvent) error {
+	log.Printf("Event %s %s %s: %s", event.EventType, event.AggregateType, event.AggregateID, event.Payload)
+	return nil
+}
+
+// NATSSink publishes events to JetStream on "<prefix>.<event type>". Each
+// message carries the event ID as its Nats-Msg-Id, which the stream uses to
+// drop duplicates, and a publish only counts once the stream has acknowledged
+// storing it. A stream must capture the subjects, or every publish fails.
+type NATSSink struct {
+	conn          *nats.Conn
+	js            jetstream.JetStream
+	subjectPrefix string
+	timeout       time.Duration
+}
+
+func NewNATSSink(rawURL, subjectPrefix string, timeout time.Duration) (*NATSSink, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "nats" || parsed.Host == "" {
+		return nil, fmt.Errorf("NATS_URL must look like nats://host:4222")
+	}
+	if subjectPrefix == "" {
+		return nil, fmt.Errorf("NATS_SUBJECT_PREFIX cannot be empty")
+	}
+
+	// The connection is retried in the background, so an unreachable server
+	// holds events in the outbox instead of stopping the engine.
+	conn, err := nats.Connect(rawURL,
+		nats.Name("sermorpheus-outbox"),
+		nats.Timeout(timeout),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	return &NATSSink{
+		conn:          conn,
+		js:            js,
+		subjectPrefix: subjectPrefix,
+		timeout:       timeout,
+	}, nil
+}
+
+func (s *NATSSink) Name() string { return "nats" }
+
+func (s *NATSSink) Publish(event *models.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	msg := nats.NewMsg(s.subjectPrefix + "." + event.EventType)
+	msg.Data = []byte(event.Payload)
+	if _, err := s.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.ID.String())); err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	return nil
+}
+
+// KafkaRESTSink produces events to a Kafka topic through a Confluent-style
+// REST proxy (Confluent REST Proxy, Redpanda HTTP Proxy). Records are keyed
+// by aggregate ID so events of one transaction land on one partition.
+type KafkaRESTSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+type kafkaRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+func NewKafkaRESTSink(baseURL, topic string, timeout time.Duration) (*KafkaRESTSink, error) {
+	if err := validateWebhookURL(baseURL); err != nil {
+		return nil, fmt.Errorf("KAFKA_REST_URL must be an absolute http or https URL")
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("KAFKA_TOPIC cannot be empty")
+	}
+
+	return &KafkaRESTSink{
+		endpoint: strings.TrimRight(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s *KafkaRESTSink) Name() string { return "kafka" }
+
+func (s *KafkaRESTSink) Publish(event *models.OutboxEvent) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: event.AggregateID.String(), Value: json.RawMessage(event.Payload)}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponseLog))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("kafka proxy responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var produced kafkaProduceResponse
+	if err := json.Unmarshal(respBody, &produced); err == nil {
+		for _, offset := range produced.Offsets {
+			if offset.ErrorCode != nil {
+				return fmt.Errorf("kafka proxy rejected record: %s", offset.Error)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sermorpheus-engine-test/internal/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Payment check states and results.
+const (
+	PaymentCheckRunning   = "running"
+	PaymentCheckCompleted = "completed"
+	PaymentCheckTimedOut  = "timed_out"
+	PaymentCheckFailed    = "failed"
+
+	PaymentCheckPaid                  = "paid"
+	PaymentCheckAwaitingConfirmations = "awaiting_confirmations"
+	PaymentCheckNotFound              = "not_found"
+	PaymentCheckLate                  = "late"
+
+	PaymentCandidateMatched     = "matched"
+	PaymentCandidateWrongToken  = "wrong_token"
+	PaymentCandidateWrongAmount = "amount_mismatch"
+
+	maxCheckCandidates = 50
+	paymentCheckPoll   = 250 * time.Millisecond
+)
+
+// PaymentCheckService runs manual payment checks. A check scans recent blocks
+// with a bounded timeout and is stored so its result can be fetched from any
+// replica. Only one check runs per transaction at a time; asking again while
+// one is running joins it.
+type PaymentCheckService struct {
+	db         *gorm.DB
+	blockchain *BlockchainService
+	timeout    time.Duration
+
+	mu      sync.Mutex
+	running map[uuid.UUID]*runningCheck
+}
+
+type runningCheck struct {
+	id   uuid.UUID
+	done chan struct{}
+}
+
+func NewPaymentCheckService(db *gorm.DB, blockchain *BlockchainService, timeout time.Duration) *PaymentCheckService {
+	return &PaymentCheckService{
+		db:         db,
+		blockchain: blockchain,
+		timeout:    timeout,
+		running:    make(map[uuid.UUID]*runningCheck),
+	}
+}
+
+// Start begins a check for a pending transaction, or returns the check
+// already running for it on this or another replica.
+func (pc *PaymentCheckService) Start(transaction *models.Transaction) (*models.PaymentCheck, error) {
+	watcher, exists := pc.blockchain.watchers[transaction.ChainID]
+	if !exists {
+		return nil, ErrChainNotSupported
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if running, ok := pc.running[transaction.ID]; ok {
+		return pc.Get(transaction.ID, running.id)
+	}
+
+	// A replica that died mid-check leaves its row running; give up on it
+	// once it is well past the timeout so new checks can start.
+	now := time.Now()
+	if err := pc.db.Model(&models.PaymentCheck{}).
+		Where("transaction_id = ? AND status = ? AND started_at < ?", transaction.ID, PaymentCheckRunning, now.Add(-2*pc.timeout)).
+		Updates(map[string]interface{}{"status": PaymentCheckFailed, "error": "check abandoned", "finished_at": now}).Error; err != nil {
+		return nil, err
+	}
+
+	check := &models.PaymentCheck{
+		TransactionID: transaction.ID,
+		ChainID:       transaction.ChainID,
+		Status:        PaymentCheckRunning,
+		Candidates:    []models.PaymentCheckCandidate{},
+		StartedAt:     now,
+	}
+	if err := pc.db.Create(check).Error; err != nil {
+		var existing models.PaymentCheck
+		if lookupErr := pc.db.Where("transaction_id = ? AND status = ?", transaction.ID, PaymentCheckRunning).
+			First(&existing).Error; lookupErr == nil {
+			return &existing, nil
+		}
+		return nil, err
+	}
+
+	running := &runningCheck{id: check.ID, done: make(chan struct{})}
+	pc.running[transaction.ID] = running
+	scan := *check
+	go pc.run(watcher, *transaction, &scan, running)
+
+	return check, nil
+}
+
+func (pc *PaymentCheckService) run(watcher *ChainWatcher, transaction models.Transaction, check *models.PaymentCheck, running *runningCheck) {
+	ctx, cancel := context.WithTimeout(context.Background(), pc.timeout)
+	defer cancel()
+
+	err := watcher.scanForPayment(ctx, &transaction, check)
+
+	now := time.Now()
+	check.FinishedAt = &now
+	switch {
+	case err == nil:
+		check.Status = PaymentCheckCompleted
+	case ctx.Err() != nil:
+		check.Status = PaymentCheckTimedOut
+		check.Error = fmt.Sprintf("check stopped after %s", pc.timeout)
+	default:
+		check.Status = PaymentCheckFailed
+		check.Error = err.Error()
+	}
+	log.Printf("Payment check %s for transaction %s: %s %s, %d blocks, %d candidates",
+		check.ID, transaction.ID, check.Status, check.Result, check.BlocksScanned, len(check.Candidates))
+
+	if err := pc.db.Model(check).Select("*").Updates(check).Error; err != nil {
+		log.Printf("Failed to save payment check %s: %v", check.ID, err)
+	}
+
+	pc.mu.Lock()
+	delete(pc.running, transaction.ID)
+	pc.mu.Unlock()
+	close(running.done)
+}
+
+// Wait returns the check once it has finished, or its current state after
+// wait has passed.
+func (pc *PaymentCheckService) Wait(ctx context.Context, check *models.PaymentCheck, wait time.Duration) (*models.PaymentCheck, error) {
+	if check.Status != PaymentCheckRunning {
+		return check, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	pc.mu.Lock()
+	running, local := pc.running[check.TransactionID]
+	pc.mu.Unlock()
+
+	if local && running.id == check.ID {
+		select {
+		case <-running.done:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		return pc.Get(check.TransactionID, check.ID)
+	}
+
+	// Running on another replica: follow it through the database.
+	ticker := time.NewTicker(paymentCheckPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return pc.Get(check.TransactionID, check.ID)
+		case <-ctx.Done():
+			return pc.Get(check.TransactionID, check.ID)
+		case <-ticker.C:
+			current, err := pc.Get(check.TransactionID, check.ID)
+			if err != nil || current.Status != PaymentCheckRunning {
+				return current, err
+			}
+		}
+	}
+}
+
+func (pc *PaymentCheckService) Get(transactionID, checkID uuid.UUID) (*models.PaymentCheck, error) {
+	var check models.PaymentCheck
+	if err := pc.db.Where("id = ? AND transaction_id = ?", checkID, transactionID).
+		First(&check).Error; err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
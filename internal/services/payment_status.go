@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sermorpheus-engine-test/internal/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	paymentStatusChannel    = "payment_status"
+	paymentStatusBuffer     = 32
+	paymentListenMaxBackoff = 30 * time.Second
+)
+
+// Payment status updates streamed to checkout pages.
+const (
+	PaymentStatusSnapshot   = "status"
+	PaymentTransferDetected = "transfer_detected"
+	PaymentConfirmations    = "confirmations"
+	PaymentPaid             = "paid"
+	PaymentExpired          = "expired"
+	PaymentCancelled        = "cancelled"
+	PaymentReminder         = "reminder"
+
+	// paymentResync tells subscribers updates may have been missed while the
+	// hub was disconnected from the database.
+	paymentResync = "resync"
+)
+
+type PaymentStatusUpdate struct {
+	Type                  string     `json:"type"`
+	TransactionID         uuid.UUID  `json:"transaction_id"`
+	Status                string     `json:"status,omitempty"`
+	PaymentDeadline       *time.Time `json:"payment_deadline,omitempty"`
+	MinutesLeft           int        `json:"minutes_left,omitempty"`
+	TxHash                string     `json:"tx_hash,omitempty"`
+	BlockNumber           uint64     `json:"block_number,omitempty"`
+	Confirmations         int        `json:"confirmations,omitempty"`
+	RequiredConfirmations int        `json:"required_confirmations,omitempty"`
+	Amount                string     `json:"amount,omitempty"`
+	Token                 string     `json:"token,omitempty"`
+	At                    time.Time  `json:"at"`
+}
+
+// Final reports whether no further updates will follow.
+func (u *PaymentStatusUpdate) Final() bool {
+	switch u.Status {
+	case "pending", "":
+		return false
+	}
+	return true
+}
+
+// IsResync reports whether the update only asks the subscriber to reload the
+// transaction.
+func (u *PaymentStatusUpdate) IsResync() bool {
+	return u.Type == paymentResync
+}
+
+// CurrentPaymentStatus is the update describing a transaction as it is now.
+func CurrentPaymentStatus(transaction *models.Transaction) PaymentStatusUpdate {
+	update := PaymentStatusUpdate{
+		Type:          PaymentStatusSnapshot,
+		TransactionID: transaction.ID,
+		Status:        transaction.Status,
+		At:            time.Now(),
+	}
+	if transaction.Status == "pending" {
+		update.PaymentDeadline = transaction.PaymentDeadline
+	}
+	return update
+}
+
+// notifyPaymentStatus broadcasts an update to every replica through Postgres
+// NOTIFY. Sent inside a database transaction, the update is only delivered
+// if the transaction commits.
+func notifyPaymentStatus(db *gorm.DB, update PaymentStatusUpdate) error {
+	update.At = time.Now()
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	return db.Exec("SELECT pg_notify(?, ?)", paymentStatusChannel, string(payload)).Error
+}
+
+// PaymentStatusHub listens for payment status updates from every replica and
+// hands them to the local subscribers of each transaction. It also remembers
+// the latest progress update of pending transactions so late subscribers see
+// a transfer that has already been detected.
+type PaymentStatusHub struct {
+	databaseURL string
+
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan PaymentStatusUpdate]struct{}
+	progress    map[uuid.UUID]PaymentStatusUpdate
+}
+
+func NewPaymentStatusHub(databaseURL string) *PaymentStatusHub {
+	return &PaymentStatusHub{
+		databaseURL: databaseURL,
+		subscribers: make(map[uuid.UUID]map[chan PaymentStatusUpdate]struct{}),
+		progress:    make(map[uuid.UUID]PaymentStatusUpdate),
+	}
+}
+
+// Start keeps a dedicated database connection listening for updates,
+// reconnecting with backoff when it drops.
+func (h *PaymentStatusHub) Start() {
+	go func() {
+		wait := time.Second
+		for {
+			started := time.Now()
+			err := h.listen(context.Background())
+			log.Printf("Payment status listener stopped: %v", err)
+
+			if time.Since(started) > paymentListenMaxBackoff {
+				wait = time.Second
+			}
+			time.Sleep(wait)
+			wait = min(wait*2, paymentListenMaxBackoff)
+		}
+	}()
+}
+
+func (h *PaymentStatusHub) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, h.databaseURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+paymentStatusChannel); err != nil {
+		return err
+	}
+	h.resyncAll()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var update PaymentStatusUpdate
+		if err := json.Unmarshal([]byte(notification.Payload), &update); err != nil {
+			log.Printf("Ignoring malformed payment status update: %v", err)
+			continue
+		}
+		h.dispatch(update)
+	}
+}
+
+// Subscribe returns the updates for one transaction and a function that
+// ends the subscription. The channel is closed if the subscriber falls too
+// far behind; it should then reload the transaction.
+func (h *PaymentStatusHub) Subscribe(transactionID uuid.UUID) (<-chan PaymentStatusUpdate, func()) {
+	updates := make(chan PaymentStatusUpdate, paymentStatusBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[transactionID] == nil {
+		h.subscribers[transactionID] = make(map[chan PaymentStatusUpdate]struct{})
+	}
+	h.subscribers[transactionID][updates] = struct{}{}
+	h.mu.Unlock()
+
+	return updates, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(transactionID, updates)
+	}
+}
+
+// Progress returns the latest transfer_detected or confirmations update seen
+// for a pending transaction.
+func (h *PaymentStatusHub) Progress(transactionID uuid.UUID) (PaymentStatusUpdate, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	update, ok := h.progress[transactionID]
+	return update, ok
+}
+
+func (h *PaymentStatusHub) dispatch(update PaymentStatusUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch {
+	case update.Final():
+		delete(h.progress, update.TransactionID)
+	case update.Type == PaymentTransferDetected || update.Type == PaymentConfirmations:
+		h.progress[update.TransactionID] = update
+	}
+
+	for updates := range h.subscribers[update.TransactionID] {
+		select {
+		case updates <- update:
+		default:
+			h.remove(update.TransactionID, updates)
+		}
+	}
+}
+
+func (h *PaymentStatusHub) resyncAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for transactionID, subscribers := range h.subscribers {
+		for updates := range subscribers {
+			select {
+			case updates <- PaymentStatusUpdate{Type: paymentResync, TransactionID: transactionID, At: time.Now()}:
+			default:
+				h.remove(transactionID, updates)
+			}
+		}
+	}
+}
+
+// remove must be called with mu held.
+func (h *PaymentStatusHub) remove(transactionID uuid.UUID, updates chan PaymentStatusUpdate) {
+	subscribers := h.subscribers[transactionID]
+	if _, ok := subscribers[updates]; !ok {
+		return
+	}
+	delete(subscribers, updates)
+	close(updates)
+	if len(subscribers) == 0 {
+		delete(h.subscribers, transactionID)
+	}
+}
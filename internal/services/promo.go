@@ -0,0 +1,380 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PromoDiscountPercent = "percent"
+	PromoDiscountFixed   = "fixed"
+)
+
+// PromoService manages promo codes. Discounts are taken off the ticket total
+// before the platform fee and the token conversion. A redemption is recorded
+// in the same database transaction that creates the booking and released
+// again when the booking expires or is cancelled.
+type PromoService struct {
+	db *gorm.DB
+}
+
+type CreatePromoCodeRequest struct {
+	Code           string
+	Description    string
+	DiscountType   string
+	PercentBps     int64
+	AmountIDR      money.IDR
+	MaxDiscountIDR money.IDR
+	MinOrderIDR    money.IDR
+	EventID        *uuid.UUID
+	MaxRedemptions int
+	MaxPerCustomer int
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+}
+
+type UpdatePromoCodeRequest struct {
+	Description    *string
+	PercentBps     *int64
+	AmountIDR      *money.IDR
+	MaxDiscountIDR *money.IDR
+	MinOrderIDR    *money.IDR
+	MaxRedemptions *int
+	MaxPerCustomer *int
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+	Status         *string
+}
+
+func NewPromoService(db *gorm.DB) *PromoService {
+	return &PromoService{db: db}
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Preview returns the discount a promo code would give on a booking without
+// redeeming it. Per-customer limits are only checked on redemption.
+func (ps *PromoService) Preview(code string, event *models.Event, totalIDR money.IDR) (*models.PromoCode, money.IDR, error) {
+	promo, err := findPromoCode(ps.db, code)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	discount, err := checkPromoCode(promo, event, totalIDR, time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+	return promo, discount, nil
+}
+
+// reserve locks the promo code and checks every limit for the customer. The
+// caller must call recordRedemption in the same transaction once the booking
+// exists.
+func (ps *PromoService) reserve(tx *gorm.DB, code string, event *models.Event, customerID uuid.UUID, totalIDR money.IDR) (*models.PromoCode, money.IDR, error) {
+	promo, err := findPromoCode(tx.Clauses(clause.Locking{Strength: "UPDATE"}), code)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	discount, err := checkPromoCode(promo, event, totalIDR, time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if promo.MaxPerCustomer > 0 {
+		var used int64
+		if err := tx.Model(&models.PromoRedemption{}).
+			Where("promo_code_id = ? AND customer_id = ? AND status = ?", promo.ID, customerID, "redeemed").
+			Count(&used).Error; err != nil {
+			return nil, 0, err
+		}
+		if used >= int64(promo.MaxPerCustomer) {
+			return nil, 0, ErrPromoCustomerLimit
+		}
+	}
+
+	return promo, discount, nil
+}
+
+func (ps *PromoService) recordRedemption(tx *gorm.DB, promo *models.PromoCode, transaction *models.Transaction) error {
+	redemption := &models.PromoRedemption{
+		PromoCodeID:   promo.ID,
+		TransactionID: transaction.ID,
+		CustomerID:    transaction.CustomerID,
+		DiscountIDR:   transaction.DiscountIDR,
+		Status:        "redeemed",
+	}
+	if err := tx.Create(redemption).Error; err != nil {
+		return err
+	}
+
+	result := tx.Model(&models.PromoCode{}).
+		Where("id = ? AND (max_redemptions = 0 OR redeemed_count < max_redemptions)", promo.ID).
+		UpdateColumn("redeemed_count", gorm.Expr("redeemed_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPromoExhausted
+	}
+	return nil
+}
+
+// releasePromoRedemption gives the promo code use of an expired or cancelled
+// booking back.
+func releasePromoRedemption(tx *gorm.DB, transactionID uuid.UUID) error {
+	var redemption models.PromoRedemption
+	err := tx.Where("transaction_id = ? AND status = ?", transactionID, "redeemed").
+		First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result := tx.Model(&models.PromoRedemption{}).
+		Where("id = ? AND status = ?", redemption.ID, "redeemed").
+		Updates(map[string]interface{}{"status": "released", "released_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	return tx.Model(&models.PromoCode{}).
+		Where("id = ? AND redeemed_count > 0", redemption.PromoCodeID).
+		UpdateColumn("redeemed_count", gorm.Expr("redeemed_count - 1")).Error
+}
+
+func findPromoCode(db *gorm.DB, code string) (*models.PromoCode, error) {
+	var promo models.PromoCode
+	if err := db.First(&promo, "code = ?", normalizePromoCode(code)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPromoInvalid
+		}
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func checkPromoCode(promo *models.PromoCode, event *models.Event, totalIDR money.IDR, now time.Time) (money.IDR, error) {
+	if promo.Status != "active" {
+		return 0, ErrPromoInvalid
+	}
+	if promo.StartsAt != nil && now.Before(*promo.StartsAt) {
+		return 0, ErrPromoNotActive
+	}
+	if promo.EndsAt != nil && !now.Before(*promo.EndsAt) {
+		return 0, ErrPromoNotActive
+	}
+	if promo.EventID != nil && *promo.EventID != event.ID {
+		return 0, ErrPromoNotApplicable
+	}
+	if totalIDR < promo.MinOrderIDR {
+		return 0, ErrPromoNotApplicable
+	}
+	if promo.MaxRedemptions > 0 && promo.RedeemedCount >= promo.MaxRedemptions {
+		return 0, ErrPromoExhausted
+	}
+
+	return promoDiscount(promo, totalIDR), nil
+}
+
+// promoDiscount never takes more than the ticket total off.
+func promoDiscount(promo *models.PromoCode, totalIDR money.IDR) money.IDR {
+	var discount money.IDR
+	switch promo.DiscountType {
+	case PromoDiscountPercent:
+		discount = totalIDR.MulBasisPoints(promo.PercentBps)
+		if promo.MaxDiscountIDR > 0 && discount > promo.MaxDiscountIDR {
+			discount = promo.MaxDiscountIDR
+		}
+	case PromoDiscountFixed:
+		discount = promo.AmountIDR
+	}
+	return min(discount, totalIDR)
+}
+
+func (ps *PromoService) ListPromoCodes() ([]models.PromoCode, error) {
+	var promos []models.PromoCode
+	if err := ps.db.Order("created_at DESC").Find(&promos).Error; err != nil {
+		return nil, err
+	}
+	return promos, nil
+}
+
+func (ps *PromoService) GetPromoRedemptions(id uuid.UUID) ([]models.PromoRedemption, error) {
+	var redemptions []models.PromoRedemption
+	if err := ps.db.Where("promo_code_id = ?", id).
+		Order("created_at DESC").
+		Find(&redemptions).Error; err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+func (ps *PromoService) CreatePromoCode(req *CreatePromoCodeRequest) (*models.PromoCode, error) {
+	promo := &models.PromoCode{
+		Code:           normalizePromoCode(req.Code),
+		Description:    req.Description,
+		DiscountType:   strings.ToLower(strings.TrimSpace(req.DiscountType)),
+		PercentBps:     req.PercentBps,
+		AmountIDR:      req.AmountIDR,
+		MaxDiscountIDR: req.MaxDiscountIDR,
+		MinOrderIDR:    req.MinOrderIDR,
+		EventID:        req.EventID,
+		MaxRedemptions: req.MaxRedemptions,
+		MaxPerCustomer: req.MaxPerCustomer,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		Status:         "active",
+	}
+
+	if promo.Code == "" {
+		return nil, errors.New("code is required")
+	}
+	if err := validatePromoCode(promo); err != nil {
+		return nil, err
+	}
+
+	if promo.EventID != nil {
+		var count int64
+		if err := ps.db.Model(&models.Event{}).Where("id = ?", *promo.EventID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrEventNotFound
+		}
+	}
+
+	var existing int64
+	if err := ps.db.Model(&models.PromoCode{}).Where("code = ?", promo.Code).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("promo code %s already exists", promo.Code)
+	}
+
+	if err := ps.db.Create(promo).Error; err != nil {
+		return nil, err
+	}
+	return promo, nil
+}
+
+// promoEditableFields are the fields UpdatePromoCode writes.
+var promoEditableFields = []string{
+	"Description", "PercentBps", "AmountIDR", "MaxDiscountIDR", "MinOrderIDR",
+	"MaxRedemptions", "MaxPerCustomer", "StartsAt", "EndsAt", "Status", "UpdatedAt",
+}
+
+func (ps *PromoService) UpdatePromoCode(id uuid.UUID, req *UpdatePromoCodeRequest) (*models.PromoCode, error) {
+	var promo models.PromoCode
+
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&promo, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		if req.Description != nil {
+			promo.Description = *req.Description
+		}
+		if req.PercentBps != nil {
+			promo.PercentBps = *req.PercentBps
+		}
+		if req.AmountIDR != nil {
+			promo.AmountIDR = *req.AmountIDR
+		}
+		if req.MaxDiscountIDR != nil {
+			promo.MaxDiscountIDR = *req.MaxDiscountIDR
+		}
+		if req.MinOrderIDR != nil {
+			promo.MinOrderIDR = *req.MinOrderIDR
+		}
+		if req.MaxRedemptions != nil {
+			promo.MaxRedemptions = *req.MaxRedemptions
+		}
+		if req.MaxPerCustomer != nil {
+			promo.MaxPerCustomer = *req.MaxPerCustomer
+		}
+		if req.StartsAt != nil {
+			promo.StartsAt = req.StartsAt
+		}
+		if req.EndsAt != nil {
+			promo.EndsAt = req.EndsAt
+		}
+		if req.Status != nil {
+			if *req.Status != "active" && *req.Status != "disabled" {
+				return errors.New("status must be active or disabled")
+			}
+			promo.Status = *req.Status
+		}
+
+		if err := validatePromoCode(&promo); err != nil {
+			return err
+		}
+
+		// redeemed_count is left out: it belongs to redemptions, which
+		// change it with relative updates.
+		return tx.Model(&promo).Select(promoEditableFields).Updates(&promo).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &promo, nil
+}
+
+// DisablePromoCode stops a code from being redeemed. Existing redemptions are
+// kept.
+func (ps *PromoService) DisablePromoCode(id uuid.UUID) error {
+	result := ps.db.Model(&models.PromoCode{}).
+		Where("id = ?", id).
+		Update("status", "disabled")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func validatePromoCode(promo *models.PromoCode) error {
+	switch promo.DiscountType {
+	case PromoDiscountPercent:
+		if promo.PercentBps <= 0 || promo.PercentBps > 10000 {
+			return errors.New("percent_bps must be between 1 and 10000")
+		}
+	case PromoDiscountFixed:
+		if promo.AmountIDR <= 0 {
+			return errors.New("amount_idr must be positive")
+		}
+	default:
+		return fmt.Errorf("discount_type must be %s or %s", PromoDiscountPercent, PromoDiscountFixed)
+	}
+
+	if promo.MaxDiscountIDR < 0 || promo.MinOrderIDR < 0 {
+		return errors.New("amounts cannot be negative")
+	}
+	if promo.MaxRedemptions < 0 || promo.MaxPerCustomer < 0 {
+		return errors.New("usage limits cannot be negative")
+	}
+	if promo.StartsAt != nil && promo.EndsAt != nil && !promo.EndsAt.After(*promo.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPromoDiscount(t *testing.T) {
+	tests := []struct {
+		name  string
+		promo models.PromoCode
+		total money.IDR
+		want  money.IDR
+	}{
+		{name: "percent", promo: models.PromoCode{DiscountType: PromoDiscountPercent, PercentBps: 1000}, total: 250000, want: 25000},
+		{name: "percent rounds half up", promo: models.PromoCode{DiscountType: PromoDiscountPercent, PercentBps: 1500}, total: 10010, want: 1502},
+		{name: "percent capped", promo: models.PromoCode{DiscountType: PromoDiscountPercent, PercentBps: 5000, MaxDiscountIDR: 50000}, total: 250000, want: 50000},
+		{name: "full percent", promo: models.PromoCode{DiscountType: PromoDiscountPercent, PercentBps: 10000}, total: 250000, want: 250000},
+		{name: "fixed", promo: models.PromoCode{DiscountType: PromoDiscountFixed, AmountIDR: 20000}, total: 250000, want: 20000},
+		{name: "fixed above total", promo: models.PromoCode{DiscountType: PromoDiscountFixed, AmountIDR: 300000}, total: 250000, want: 250000},
+		{name: "unknown type", promo: models.PromoCode{DiscountType: "bogus", AmountIDR: 20000}, total: 250000, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := promoDiscount(&tt.promo, tt.total); got != tt.want {
+			t.Errorf("%s: promoDiscount = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPromoCode(t *testing.T) {
+	now := time.Date(2025, 7, 30, 12, 0, 0, 0, time.UTC)
+	earlier, later := now.Add(-time.Hour), now.Add(time.Hour)
+	event := &models.Event{ID: uuid.New()}
+	otherEvent := uuid.New()
+
+	active := func(modify func(*models.PromoCode)) models.PromoCode {
+		promo := models.PromoCode{Status: "active", DiscountType: PromoDiscountFixed, AmountIDR: 10000}
+		if modify != nil {
+			modify(&promo)
+		}
+		return promo
+	}
+
+	tests := []struct {
+		name    string
+		promo   models.PromoCode
+		total   money.IDR
+		want    money.IDR
+		wantErr error
+	}{
+		{name: "valid", promo: active(nil), total: 100000, want: 10000},
+		{name: "disabled", promo: active(func(p *models.PromoCode) { p.Status = "disabled" }), total: 100000, wantErr: ErrPromoInvalid},
+		{name: "not started", promo: active(func(p *models.PromoCode) { p.StartsAt = &later }), total: 100000, wantErr: ErrPromoNotActive},
+		{name: "started", promo: active(func(p *models.PromoCode) { p.StartsAt = &now }), total: 100000, want: 10000},
+		{name: "ended", promo: active(func(p *models.PromoCode) { p.EndsAt = &earlier }), total: 100000, wantErr: ErrPromoNotActive},
+		{name: "ends now", promo: active(func(p *models.PromoCode) { p.EndsAt = &now }), total: 100000, wantErr: ErrPromoNotActive},
+		{name: "same event", promo: active(func(p *models.PromoCode) { p.EventID = &event.ID }), total: 100000, want: 10000},
+		{name: "other event", promo: active(func(p *models.PromoCode) { p.EventID = &otherEvent }), total: 100000, wantErr: ErrPromoNotApplicable},
+		{name: "below minimum order", promo: active(func(p *models.PromoCode) { p.MinOrderIDR = 200000 }), total: 100000, wantErr: ErrPromoNotApplicable},
+		{name: "at minimum order", promo: active(func(p *models.PromoCode) { p.MinOrderIDR = 100000 }), total: 100000, want: 10000},
+		{name: "exhausted", promo: active(func(p *models.PromoCode) { p.MaxRedemptions = 5; p.RedeemedCount = 5 }), total: 100000, wantErr: ErrPromoExhausted},
+		{name: "last redemption", promo: active(func(p *models.PromoCode) { p.MaxRedemptions = 5; p.RedeemedCount = 4 }), total: 100000, want: 10000},
+	}
+
+	for _, tt := range tests {
+		got, err := checkPromoCode(&tt.promo, event, tt.total, now)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: checkPromoCode error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: checkPromoCode returned error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: checkPromoCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePromoCode(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		promo   models.PromoCode
+		wantErr bool
+	}{
+		{name: "percent", promo: models.PromoCode{DiscountType: PromoDiscountPercent, PercentBps: 1000}},
+		{name: "fixed", promo: models.PromoCode{DiscountType: PromoDiscountFixed, AmountIDR: 10000}},
+		{name: "zero percent", promo: models.PromoCode{DiscountType: PromoDiscountPercent}, wantErr: true},
+		{name: "percent above 100", promo: models.PromoCode{DiscountType: PromoDiscountPercent, PercentBps: 10001}, wantErr: true},
+		{name: "zero fixed", promo: models.PromoCode{DiscountType: PromoDiscountFixed}, wantErr: true},
+		{name: "unknown type", promo: models.PromoCode{DiscountType: "bogus", AmountIDR: 10000}, wantErr: true},
+		{name: "negative minimum", promo: models.PromoCode{DiscountType: PromoDiscountFixed, AmountIDR: 10000, MinOrderIDR: -1}, wantErr: true},
+		{name: "negative limit", promo: models.PromoCode{DiscountType: PromoDiscountFixed, AmountIDR: 10000, MaxPerCustomer: -1}, wantErr: true},
+		{name: "window", promo: models.PromoCode{DiscountType: PromoDiscountFixed, AmountIDR: 10000, StartsAt: &now, EndsAt: &later}},
+		{name: "inverted window", promo: models.PromoCode{DiscountType: PromoDiscountFixed, AmountIDR: 10000, StartsAt: &later, EndsAt: &now}, wantErr: true},
+		{name: "empty window", promo: models.PromoCode{DiscountType: PromoDiscountFixed, AmountIDR: 10000, StartsAt: &now, EndsAt: &now}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := validatePromoCode(&tt.promo); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validatePromoCode error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizePromoCode(t *testing.T) {
+	if got := normalizePromoCode("  summer25 "); got != "SUMMER25" {
+		t.Errorf("normalizePromoCode = %q, want SUMMER25", got)
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type QuoteService struct {
+	db           *gorm.DB
+	eventService *EventService
+	rateService  *RateService
+	chains       *ChainRegistry
+	feeService   *FeeService
+	promoService *PromoService
+	ttl          time.Duration
+}
+
+type CreateQuoteRequest struct {
+	EventID  uuid.UUID `json:"event_id"`
+	Quantity int       `json:"quantity"`
+	ChainID  int64     `json:"chain_id,omitempty"`
+	Token    string    `json:"token,omitempty"`
+
+	PromoCode string `json:"promo_code,omitempty"`
+}
+
+func NewQuoteService(db *gorm.DB, eventService *EventService, rateService *RateService, chains *ChainRegistry, feeService *FeeService, promoService *PromoService, ttl time.Duration) *QuoteService {
+	return &QuoteService{
+		db:           db,
+		eventService: eventService,
+		rateService:  rateService,
+		chains:       chains,
+		feeService:   feeService,
+		promoService: promoService,
+		ttl:          ttl,
+	}
+}
+
+func (qs *QuoteService) CreateQuote(req *CreateQuoteRequest) (*models.Quote, error) {
+	event, err := qs.eventService.GetEventByID(req.EventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+
+	if err := qs.eventService.CheckSalesWindow(event, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if event.MaxTicketsPerTransaction > 0 && req.Quantity > event.MaxTicketsPerTransaction {
+		return nil, ErrMaxPerTransaction
+	}
+
+	if event.AvailableQuota < req.Quantity {
+		return nil, ErrInsufficientTickets
+	}
+
+	token, err := qs.chains.Resolve(req.ChainID, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := qs.rateService.GetTokenRate(token)
+	if err != nil {
+		return nil, ErrRateUnavailable
+	}
+
+	totalIDR := event.PriceIDR * money.IDR(req.Quantity)
+
+	var promoCode string
+	var discountIDR money.IDR
+	if req.PromoCode != "" {
+		promo, discount, err := qs.promoService.Preview(req.PromoCode, event, totalIDR)
+		if err != nil {
+			return nil, err
+		}
+		promoCode = promo.Code
+		discountIDR = discount
+	}
+
+	fee, err := qs.feeService.Calculate(event, token.ChainID, totalIDR-discountIDR)
+	if err != nil {
+		return nil, err
+	}
+	price := priceBooking(rate, token, fee)
+
+	quote := &models.Quote{
+		EventID:            event.ID,
+		Quantity:           req.Quantity,
+		TotalIDR:           totalIDR,
+		PromoCode:          promoCode,
+		DiscountIDR:        discountIDR,
+		BaseIDR:            fee.BaseIDR,
+		FeeIDR:             fee.FeeIDR,
+		NetworkFeeIDR:      fee.NetworkFeeIDR,
+		ChargedIDR:         fee.ChargedIDR(),
+		FeeRuleID:          fee.RuleID,
+		FeeRule:            fee.Rule,
+		RateID:             rate.RateID,
+		USDTRate:           price.USDTRate,
+		USDTAmount:         price.USDTAmount,
+		ChainID:            token.ChainID,
+		PaymentToken:       token.Symbol,
+		TokenRate:          price.TokenRate,
+		TokenAmount:        price.TokenAmount,
+		TokenDecimals:      price.TokenDecimals,
+		PlatformFeePercent: float64(fee.PercentBps) / 100,
+		ExpiresAt:          time.Now().Add(qs.ttl),
+	}
+
+	if err := qs.db.Create(quote).Error; err != nil {
+		return nil, err
+	}
+
+	return quote, nil
+}
+
+func (qs *QuoteService) GetQuoteByID(id uuid.UUID) (*models.Quote, error) {
+	var quote models.Quote
+	if err := qs.db.First(&quote, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+func (qs *QuoteService) claimQuote(tx *gorm.DB, quoteID uuid.UUID, req *CreateTransactionRequest, token *PaymentToken) (*models.Quote, error) {
+	var quote models.Quote
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&quote, "id = ?", quoteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuoteInvalid
+		}
+		return nil, err
+	}
+
+	if quote.EventID != req.EventID || quote.Quantity != req.Quantity ||
+		quote.ChainID != token.ChainID || quote.PaymentToken != token.Symbol ||
+		quote.PromoCode != normalizePromoCode(req.PromoCode) {
+		return nil, ErrQuoteInvalid
+	}
+
+	now := time.Now()
+	if !now.Before(quote.ExpiresAt) {
+		return nil, ErrQuoteExpired
+	}
+
+	result := tx.Model(&models.Quote{}).
+		Where("id = ? AND used_at IS NULL", quote.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrQuoteInvalid
+	}
+
+	quote.UsedAt = &now
+	return &quote, nil
+}
+
+func (qs *QuoteService) attachTransaction(tx *gorm.DB, quoteID, transactionID uuid.UUID) error {
+	return tx.Model(&models.Quote{}).
+		Where("id = ?", quoteID).
+		Update("transaction_id", transactionID).Error
+}
+
+type bookingPrice struct {
+	Fee           FeeBreakdown
+	USDTRate      money.Rate
+	USDTAmount    money.Units
+	TokenRate     money.Rate
+	TokenAmount   money.Units
+	TokenDecimals int
+}
+
+// priceBooking converts the charged IDR amount, fees included, into the USDT
+// reference amount and the amount of the payment token, both in base units.
+func priceBooking(rate *TokenRate, token *PaymentToken, fee *FeeBreakdown) *bookingPrice {
+	chargedIDR := fee.ChargedIDR()
+	return &bookingPrice{
+		Fee:           *fee,
+		USDTRate:      rate.USDTRate,
+		USDTAmount:    money.ToUnits(chargedIDR, rate.USDTRate, money.USDTDecimals),
+		TokenRate:     rate.IDRPerToken,
+		TokenAmount:   calculateTokenAmount(chargedIDR, rate.IDRPerToken, token.Decimals),
+		TokenDecimals: token.Decimals,
+	}
+}
@@ -1,10 +1,10 @@
 package services
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
-	"net/http"
+	"log"
+	"sync"
 	"time"
 
 	"sermorpheus-engine-test/internal/models"
@@ -13,77 +13,126 @@ import (
 )
 
 type RateService struct {
-	db *gorm.DB
+	db           *gorm.DB
+	pipeline     *RatePipeline
+	maxAge       time.Duration
+	fetchTimeout time.Duration
+	maxOverride  time.Duration
+
+	mu          sync.RWMutex
+	current     *models.USDTRate
+	override    *activeOverride
+	tokenPrices map[RatePair]*TokenPrice
 }
 
-type ExchangeRateResponse struct {
-	Rates map[string]float64 `json:"rates"`
-}
+func NewRateService(db *gorm.DB, pipeline *RatePipeline, maxAge, fetchTimeout, maxOverride time.Duration) *RateService {
+	rs := &RateService{
+		db:           db,
+		pipeline:     pipeline,
+		maxAge:       maxAge,
+		fetchTimeout: fetchTimeout,
+		maxOverride:  maxOverride,
+		tokenPrices:  make(map[RatePair]*TokenPrice),
+	}
+
+	var latest models.USDTRate
+	if err := db.Where("created_at > ? AND source <> ?", time.Now().Add(-maxAge), RateSourceManualOverride).
+		Order("created_at DESC").
+		First(&latest).Error; err == nil {
+		rs.setCurrent(&latest)
+	}
 
-func NewRateService(db *gorm.DB) *RateService {
-	return &RateService{db: db}
+	return rs
 }
 
 func (rs *RateService) GetCurrentRate() (*models.USDTRate, error) {
-	var rate models.USDTRate
-
-	fiveMinutesAgo := time.Now().Add(-5 * time.Minute)
-	err := rs.db.Where("created_at > ?", fiveMinutesAgo).
-		Order("created_at DESC").
-		First(&rate).Error
+	rs.mu.RLock()
+	override := rs.override
+	rs.mu.RUnlock()
 
-	if err == nil {
-		return &rate, nil
+	if override != nil && time.Now().Before(override.override.ExpiresAt) {
+		return override.rate, nil
 	}
 
-	usdToIdr, err := rs.fetchUSDToIDR()
-	if err != nil {
-		usdToIdr = 15420.50
-	}
+	return rs.GetMarketRate()
+}
 
-	newRate := &models.USDTRate{
-		IDRToUSDTRate: usdToIdr,
+func (rs *RateService) GetMarketRate() (*models.USDTRate, error) {
+	rs.mu.RLock()
+	rate := rs.current
+	rs.mu.RUnlock()
+
+	if rate == nil {
+		return nil, fmt.Errorf("%w: rate cache is empty", ErrNoTrustworthyRate)
 	}
 
-	if err := rs.db.Create(newRate).Error; err != nil {
-		return nil, err
+	if time.Since(rate.CreatedAt) > rs.maxAge {
+		return nil, fmt.Errorf("%w: cached rate from %s is stale", ErrNoTrustworthyRate, rate.CreatedAt.Format(time.RFC3339))
 	}
 
-	return newRate, nil
+	return rate, nil
+}
+
+func (rs *RateService) StartRefresher(interval time.Duration) {
+	go func() {
+		const minBackoff = 5 * time.Second
+		maxBackoff := 5 * time.Minute
+		if interval > maxBackoff {
+			maxBackoff = interval
+		}
+
+		failures := 0
+		for {
+			wait := interval
+			if _, err := rs.RefreshRate(); err != nil {
+				failures++
+				wait = minBackoff << (failures - 1)
+				if wait > maxBackoff || wait <= 0 {
+					wait = maxBackoff
+				}
+				log.Printf("Failed to refresh exchange rate (attempt %d, retrying in %s): %v", failures, wait, err)
+			} else {
+				failures = 0
+			}
+
+			time.Sleep(wait)
+		}
+	}()
 }
 
-func (rs *RateService) fetchUSDToIDR() (float64, error) {
-	resp, err := http.Get("https://api.exchangerate-api.com/v4/latest/USD")
+func (rs *RateService) RefreshRate() (*models.USDTRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rs.fetchTimeout)
+	defer cancel()
+
+	quote, err := rs.pipeline.Quote(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch exchange rate: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var exchangeResp ExchangeRateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&exchangeResp); err != nil {
-		return 0, fmt.Errorf("failed to decode exchange rate response: %w", err)
+	newRate := &models.USDTRate{
+		IDRToUSDTRate: quote.Rate,
+		MarketRate:    quote.MarketRate,
+		USDTUSDRate:   quote.USDTUSDRate,
+		USDIDRRate:    quote.USDIDRRate,
+		SpreadPercent: quote.SpreadPercent,
+		Source:        quote.Source,
+		Providers:     quote.Providers,
 	}
 
-	idrRate, exists := exchangeResp.Rates["IDR"]
-	if !exists {
-		return 0, errors.New("IDR rate not found in response")
+	if err := rs.db.Create(newRate).Error; err != nil {
+		return nil, err
 	}
 
-	return idrRate, nil
+	rs.setCurrent(newRate)
+	rs.refreshTokenPrices(ctx)
+	return newRate, nil
 }
 
-func (rs *RateService) CreateRate(idrToUSDT float64) (*models.USDTRate, error) {
-	if idrToUSDT <= 0 {
-		return nil, errors.New("invalid exchange rate")
-	}
+func (rs *RateService) setCurrent(rate *models.USDTRate) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
 
-	rate := &models.USDTRate{
-		IDRToUSDTRate: idrToUSDT,
+	if rs.current == nil || !rate.CreatedAt.Before(rs.current.CreatedAt) {
+		rs.current = rate
 	}
-
-	if err := rs.db.Create(rate).Error; err != nil {
-		return nil, err
-	}
-
-	return rate, nil
 }
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoTrustworthyRate = errors.New("no trustworthy exchange rate available")
+
+type RateAggregator struct {
+	providers    []RateProvider
+	maxDeviation float64
+	maxAge       time.Duration
+	minSources   int
+}
+
+type AggregatedRate struct {
+	Pair     RatePair    `json:"pair"`
+	Rate     float64     `json:"rate"`
+	Quotes   []RateQuote `json:"quotes"`
+	Rejected []string    `json:"rejected,omitempty"`
+}
+
+func NewRateAggregator(providers []RateProvider, maxDeviationPercent float64, maxAge time.Duration, minSources int) *RateAggregator {
+	if minSources < 1 {
+		minSources = 1
+	}
+	return &RateAggregator{
+		providers:    providers,
+		maxDeviation: maxDeviationPercent / 100,
+		maxAge:       maxAge,
+		minSources:   minSources,
+	}
+}
+
+func (ra *RateAggregator) Aggregate(ctx context.Context, pair RatePair) (*AggregatedRate, error) {
+	quotes := make([]*RateQuote, len(ra.providers))
+	errs := make([]error, len(ra.providers))
+
+	var wg sync.WaitGroup
+	for i, provider := range ra.providers {
+		wg.Add(1)
+		go func(i int, provider RateProvider) {
+			defer wg.Done()
+			quotes[i], errs[i] = provider.FetchRate(ctx, pair)
+		}(i, provider)
+	}
+	wg.Wait()
+
+	result := &AggregatedRate{Pair: pair}
+	var fresh []RateQuote
+	now := time.Now()
+	for i, quote := range quotes {
+		name := ra.providers[i].Name()
+		switch {
+		case errs[i] != nil:
+			result.Rejected = append(result.Rejected, fmt.Sprintf("%s: %v", name, errs[i]))
+		case quote.Rate <= 0 || math.IsNaN(quote.Rate) || math.IsInf(quote.Rate, 0):
+			result.Rejected = append(result.Rejected, fmt.Sprintf("%s: invalid rate %v", name, quote.Rate))
+		case ra.maxAge > 0 && now.Sub(quote.Timestamp) > ra.maxAge:
+			result.Rejected = append(result.Rejected, fmt.Sprintf("%s: stale rate from %s", name, quote.Timestamp.Format(time.RFC3339)))
+		default:
+			fresh = append(fresh, *quote)
+		}
+	}
+
+	if len(fresh) < ra.minSources {
+		ra.logRejected(result.Rejected)
+		return nil, fmt.Errorf("%w: %s has %d of %d required sources", ErrNoTrustworthyRate, pair, len(fresh), ra.minSources)
+	}
+
+	median := medianRate(fresh)
+	for _, quote := range fresh {
+		deviation := math.Abs(quote.Rate-median) / median
+		if ra.maxDeviation > 0 && deviation > ra.maxDeviation {
+			result.Rejected = append(result.Rejected, fmt.Sprintf("%s: rate %.2f deviates %.2f%% from median %.2f",
+				quote.Source, quote.Rate, deviation*100, median))
+			continue
+		}
+		result.Quotes = append(result.Quotes, quote)
+	}
+
+	if len(result.Quotes) < ra.minSources {
+		ra.logRejected(result.Rejected)
+		return nil, fmt.Errorf("%w: %s sources disagree", ErrNoTrustworthyRate, pair)
+	}
+
+	ra.logRejected(result.Rejected)
+	result.Rate = medianRate(result.Quotes)
+	return result, nil
+}
+
+func (ra *RateAggregator) logRejected(rejected []string) {
+	for _, reason := range rejected {
+		log.Printf("Rejected exchange rate quote: %s", reason)
+	}
+}
+
+func medianRate(quotes []RateQuote) float64 {
+	rates := make([]float64, len(quotes))
+	for i, quote := range quotes {
+		rates[i] = quote.Rate
+	}
+	sort.Float64s(rates)
+
+	mid := len(rates) / 2
+	if len(rates)%2 == 0 {
+		return (rates[mid-1] + rates[mid]) / 2
+	}
+	return rates[mid]
+}
+
+func (ar *AggregatedRate) Sources() string {
+	names := make([]string, len(ar.Quotes))
+	for i, quote := range ar.Quotes {
+		names[i] = quote.Source
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sermorpheus-engine-test/internal/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const maxHistoryBuckets = 2000
+
+var rateIntervals = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+type RateBucket struct {
+	BucketStart time.Time `json:"bucket_start"`
+	Open        float64   `json:"open"`
+	High        float64   `json:"high"`
+	Low         float64   `json:"low"`
+	Close       float64   `json:"close"`
+	Samples     int       `json:"samples"`
+}
+
+func (rs *RateService) GetRateHistory(from, to time.Time, interval string) ([]RateBucket, error) {
+	step, ok := rateIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("invalid interval %q", interval)
+	}
+
+	if !from.Before(to) {
+		return nil, errors.New("from must be before to")
+	}
+
+	if to.Sub(from)/step > maxHistoryBuckets {
+		return nil, fmt.Errorf("range too large for %s interval (max %d buckets)", interval, maxHistoryBuckets)
+	}
+
+	var raw []RateBucket
+	if err := rs.db.Raw(`
+		SELECT date_trunc(?, created_at) AS bucket_start,
+			(array_agg(idr_to_usdt_rate ORDER BY created_at ASC))[1] AS open,
+			MAX(idr_to_usdt_rate) AS high,
+			MIN(idr_to_usdt_rate) AS low,
+			(array_agg(idr_to_usdt_rate ORDER BY created_at DESC))[1] AS close,
+			COUNT(*) AS samples
+		FROM usdt_rates
+		WHERE created_at >= ? AND created_at < ? AND source <> ?
+		GROUP BY 1
+		ORDER BY 1`, interval, from, to, RateSourceManualOverride).Scan(&raw).Error; err != nil {
+		return nil, err
+	}
+
+	var candles []RateBucket
+	if err := rs.db.Raw(`
+		SELECT date_trunc(?, bucket_start) AS bucket_start,
+			(array_agg(open ORDER BY bucket_start ASC))[1] AS open,
+			MAX(high) AS high,
+			MIN(low) AS low,
+			(array_agg(close ORDER BY bucket_start DESC))[1] AS close,
+			SUM(samples) AS samples
+		FROM rate_candles
+		WHERE bucket_start >= ? AND bucket_start < ?
+		GROUP BY 1
+		ORDER BY 1`, interval, from, to).Scan(&candles).Error; err != nil {
+		return nil, err
+	}
+
+	return mergeRateBuckets(candles, raw), nil
+}
+
+// mergeRateBuckets combines downsampled candles with raw buckets. Candles
+// always cover older data than the raw rows, so a shared bucket opens with
+// the candle and closes with the raw rate.
+func mergeRateBuckets(older, newer []RateBucket) []RateBucket {
+	merged := make(map[time.Time]RateBucket, len(older)+len(newer))
+	for _, bucket := range older {
+		merged[bucket.BucketStart] = bucket
+	}
+
+	for _, bucket := range newer {
+		existing, ok := merged[bucket.BucketStart]
+		if !ok {
+			merged[bucket.BucketStart] = bucket
+			continue
+		}
+
+		if bucket.High > existing.High {
+			existing.High = bucket.High
+		}
+		if bucket.Low < existing.Low {
+			existing.Low = bucket.Low
+		}
+		existing.Close = bucket.Close
+		existing.Samples += bucket.Samples
+		merged[bucket.BucketStart] = existing
+	}
+
+	result := make([]RateBucket, 0, len(merged))
+	for _, bucket := range merged {
+		result = append(result, bucket)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].BucketStart.Before(result[j].BucketStart)
+	})
+	return result
+}
+
+func (rs *RateService) StartRetentionJob(interval, rawRetention, candleRetention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := rs.DownsampleRates(rawRetention, candleRetention); err != nil {
+				log.Printf("Failed to downsample exchange rates: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+func (rs *RateService) DownsampleRates(rawRetention, candleRetention time.Duration) error {
+	cutoff := time.Now().Add(-rawRetention).Truncate(time.Hour)
+
+	return rs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+			INSERT INTO rate_candles (bucket_start, open, high, low, close, samples, created_at, updated_at)
+			SELECT date_trunc('hour', created_at),
+				(array_agg(idr_to_usdt_rate ORDER BY created_at ASC))[1],
+				MAX(idr_to_usdt_rate),
+				MIN(idr_to_usdt_rate),
+				(array_agg(idr_to_usdt_rate ORDER BY created_at DESC))[1],
+				COUNT(*),
+				NOW(),
+				NOW()
+			FROM usdt_rates
+			WHERE created_at < ? AND source <> ?
+			GROUP BY 1
+			ON CONFLICT (bucket_start) DO UPDATE SET
+				high = GREATEST(rate_candles.high, EXCLUDED.high),
+				low = LEAST(rate_candles.low, EXCLUDED.low),
+				close = EXCLUDED.close,
+				samples = rate_candles.samples + EXCLUDED.samples,
+				updated_at = NOW()`, cutoff, RateSourceManualOverride).Error; err != nil {
+			return fmt.Errorf("failed to build candles: %w", err)
+		}
+
+		// Manual override rates are referenced by rate_overrides, the audit
+		// trail of overrides, so they are kept.
+		result := tx.Where("created_at < ? AND source <> ?", cutoff, RateSourceManualOverride).
+			Delete(&models.USDTRate{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete raw rates: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Downsampled %d exchange rates older than %s into hourly candles", result.RowsAffected, cutoff.Format(time.RFC3339))
+		}
+
+		if candleRetention > 0 {
+			if err := tx.Where("bucket_start < ?", time.Now().Add(-candleRetention)).
+				Delete(&models.RateCandle{}).Error; err != nil {
+				return fmt.Errorf("failed to delete old candles: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
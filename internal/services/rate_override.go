@@ -0,0 +1,246 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sermorpheus-engine-test/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const RateSourceManualOverride = "manual_override"
+
+type RateOverrideRequest struct {
+	Rate      float64
+	Reason    string
+	ExpiresAt time.Time
+	Actor     string
+	ClaimedBy string
+	RemoteIP  string
+}
+
+type activeOverride struct {
+	override *models.RateOverride
+	rate     *models.USDTRate
+}
+
+func (rs *RateService) SetOverride(req *RateOverrideRequest) (*models.RateOverride, error) {
+	if req.Rate <= 0 {
+		return nil, errors.New("invalid exchange rate")
+	}
+	if req.Reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	now := time.Now()
+	if !req.ExpiresAt.After(now) {
+		return nil, errors.New("expiry must be in the future")
+	}
+	if rs.maxOverride > 0 && req.ExpiresAt.Sub(now) > rs.maxOverride {
+		return nil, fmt.Errorf("override cannot last longer than %s", rs.maxOverride)
+	}
+
+	var override models.RateOverride
+	var rate models.USDTRate
+
+	err := rs.db.Transaction(func(tx *gorm.DB) error {
+		if err := rs.revokeActive(tx, req.Actor, req.ClaimedBy, req.RemoteIP, "superseded by new override"); err != nil {
+			return err
+		}
+
+		rate = models.USDTRate{
+			IDRToUSDTRate: req.Rate,
+			MarketRate:    req.Rate,
+			Source:        RateSourceManualOverride,
+			Providers:     req.Actor,
+		}
+		if err := tx.Create(&rate).Error; err != nil {
+			return err
+		}
+
+		override = models.RateOverride{
+			RateID:    rate.ID,
+			Rate:      req.Rate,
+			Reason:    req.Reason,
+			Status:    "active",
+			CreatedBy: req.Actor,
+			ExpiresAt: req.ExpiresAt,
+		}
+		if err := tx.Create(&override).Error; err != nil {
+			return err
+		}
+
+		details := map[string]interface{}{
+			"rate":       req.Rate,
+			"reason":     req.Reason,
+			"expires_at": req.ExpiresAt,
+		}
+		if market, err := rs.GetMarketRate(); err == nil {
+			details["market_rate"] = market.IDRToUSDTRate
+			details["market_rate_id"] = market.ID
+		}
+
+		return writeRateAudit(tx, "override_created", &override.ID, req.Actor, req.ClaimedBy, req.RemoteIP, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rs.setOverride(&activeOverride{override: &override, rate: &rate})
+	log.Printf("Manual rate override %.2f set by %s until %s: %s", req.Rate, req.Actor, req.ExpiresAt.Format(time.RFC3339), req.Reason)
+	return &override, nil
+}
+
+func (rs *RateService) RevokeOverride(actor, claimedBy, remoteIP string) error {
+	err := rs.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.RateOverride{}).
+			Where("status = ?", "active").
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return rs.revokeActive(tx, actor, claimedBy, remoteIP, "revoked")
+	})
+	if err != nil {
+		return err
+	}
+
+	rs.setOverride(nil)
+	return nil
+}
+
+func (rs *RateService) GetActiveOverride() *models.RateOverride {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	if rs.override == nil || !time.Now().Before(rs.override.override.ExpiresAt) {
+		return nil
+	}
+	return rs.override.override
+}
+
+func (rs *RateService) GetRateAuditLog(limit int) ([]models.RateAuditLog, error) {
+	var entries []models.RateAuditLog
+	if err := rs.db.Order("created_at DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (rs *RateService) StartOverrideSync(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := rs.SyncOverride(); err != nil {
+				log.Printf("Failed to sync rate override: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+func (rs *RateService) SyncOverride() error {
+	var expired []models.RateOverride
+	if err := rs.db.Where("status = ? AND expires_at <= ?", "active", time.Now()).
+		Find(&expired).Error; err != nil {
+		return err
+	}
+
+	for _, override := range expired {
+		err := rs.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.RateOverride{}).
+				Where("id = ? AND status = ?", override.ID, "active").
+				Update("status", "expired")
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return writeRateAudit(tx, "override_expired", &override.ID, "system", "", "", map[string]interface{}{
+				"rate":       override.Rate,
+				"expires_at": override.ExpiresAt,
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var active models.RateOverride
+	err := rs.db.Where("status = ? AND expires_at > ?", "active", time.Now()).
+		Order("created_at DESC").
+		First(&active).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		rs.setOverride(nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var rate models.USDTRate
+	if err := rs.db.First(&rate, "id = ?", active.RateID).Error; err != nil {
+		return err
+	}
+
+	rs.setOverride(&activeOverride{override: &active, rate: &rate})
+	return nil
+}
+
+func (rs *RateService) revokeActive(tx *gorm.DB, actor, claimedBy, remoteIP, reason string) error {
+	var active []models.RateOverride
+	if err := tx.Where("status = ?", "active").Find(&active).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, override := range active {
+		if err := tx.Model(&models.RateOverride{}).
+			Where("id = ?", override.ID).
+			Updates(map[string]interface{}{
+				"status":     "revoked",
+				"revoked_at": now,
+				"revoked_by": actor,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := writeRateAudit(tx, "override_revoked", &override.ID, actor, claimedBy, remoteIP, map[string]interface{}{
+			"rate":   override.Rate,
+			"reason": reason,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rs *RateService) setOverride(override *activeOverride) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.override = override
+}
+
+func writeRateAudit(tx *gorm.DB, action string, overrideID *uuid.UUID, actor, claimedBy, remoteIP string, details map[string]interface{}) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.RateAuditLog{
+		Action:     action,
+		OverrideID: overrideID,
+		Actor:      actor,
+		ClaimedBy:  claimedBy,
+		RemoteIP:   remoteIP,
+		Details:    string(data),
+	}).Error
+}
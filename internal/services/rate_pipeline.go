@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"sermorpheus-engine-test/internal/config"
+)
+
+const (
+	RateModeDerived = "derived"
+	RateModeDirect  = "direct"
+)
+
+type RatePipeline struct {
+	mode          string
+	usdIDR        *RateAggregator
+	usdtUSD       *RateAggregator
+	usdtIDR       *RateAggregator
+	tokenPrices   map[RatePair]*RateAggregator
+	spreadPercent float64
+}
+
+type PipelineRate struct {
+	Rate          float64 `json:"rate"`
+	MarketRate    float64 `json:"market_rate"`
+	USDTUSDRate   float64 `json:"usdt_usd_rate,omitempty"`
+	USDIDRRate    float64 `json:"usd_idr_rate,omitempty"`
+	SpreadPercent float64 `json:"spread_percent"`
+	Source        string  `json:"source"`
+	Providers     string  `json:"providers"`
+}
+
+func NewRatePipeline(cfg *config.Config, chains *ChainRegistry) (*RatePipeline, error) {
+	newAggregator := func(names []string) (*RateAggregator, error) {
+		providers, err := BuildRateProviders(cfg, names)
+		if err != nil {
+			return nil, err
+		}
+		return NewRateAggregator(providers, cfg.RateMaxDeviationPercent, cfg.RateMaxAge, cfg.RateMinSources), nil
+	}
+
+	if cfg.RateSpreadPercent < 0 || cfg.RateSpreadPercent >= 100 {
+		return nil, fmt.Errorf("invalid rate spread %.2f%%", cfg.RateSpreadPercent)
+	}
+
+	pipeline := &RatePipeline{
+		mode:          cfg.RateMode,
+		tokenPrices:   make(map[RatePair]*RateAggregator),
+		spreadPercent: cfg.RateSpreadPercent,
+	}
+
+	var err error
+	switch cfg.RateMode {
+	case RateModeDerived:
+		if pipeline.usdtUSD, err = newAggregator(cfg.RateUSDTUSDProviders); err != nil {
+			return nil, fmt.Errorf("USDT/USD providers: %w", err)
+		}
+		if pipeline.usdIDR, err = newAggregator(cfg.RateUSDIDRProviders); err != nil {
+			return nil, fmt.Errorf("USD/IDR providers: %w", err)
+		}
+	case RateModeDirect:
+		if pipeline.usdtIDR, err = newAggregator(cfg.RateUSDTIDRProviders); err != nil {
+			return nil, fmt.Errorf("USDT/IDR providers: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown rate mode %q", cfg.RateMode)
+	}
+
+	for _, pair := range chains.PricedPairs() {
+		var names []string
+		switch pair {
+		case PairBNBUSDT:
+			names = cfg.RateBNBUSDTProviders
+		case PairETHUSDT:
+			names = cfg.RateETHUSDTProviders
+		case PairPOLUSDT:
+			names = cfg.RatePOLUSDTProviders
+		default:
+			return nil, fmt.Errorf("no providers configured for %s", pair)
+		}
+
+		if pipeline.tokenPrices[pair], err = newAggregator(names); err != nil {
+			return nil, fmt.Errorf("%s providers: %w", pair, err)
+		}
+	}
+
+	return pipeline, nil
+}
+
+func (rp *RatePipeline) Quote(ctx context.Context) (*PipelineRate, error) {
+	result := &PipelineRate{
+		SpreadPercent: rp.spreadPercent,
+		Source:        rp.mode,
+	}
+
+	switch rp.mode {
+	case RateModeDerived:
+		usdtUSD, err := rp.usdtUSD.Aggregate(ctx, PairUSDTUSD)
+		if err != nil {
+			return nil, err
+		}
+		usdIDR, err := rp.usdIDR.Aggregate(ctx, PairUSDIDR)
+		if err != nil {
+			return nil, err
+		}
+
+		result.USDTUSDRate = usdtUSD.Rate
+		result.USDIDRRate = usdIDR.Rate
+		result.MarketRate = usdtUSD.Rate * usdIDR.Rate
+		result.Providers = fmt.Sprintf("%s=%s;%s=%s", PairUSDTUSD, usdtUSD.Sources(), PairUSDIDR, usdIDR.Sources())
+	default:
+		usdtIDR, err := rp.usdtIDR.Aggregate(ctx, PairUSDTIDR)
+		if err != nil {
+			return nil, err
+		}
+
+		result.MarketRate = usdtIDR.Rate
+		result.Providers = fmt.Sprintf("%s=%s", PairUSDTIDR, usdtIDR.Sources())
+	}
+
+	result.Rate = applySpread(result.MarketRate, rp.spreadPercent)
+	return result, nil
+}
+
+// TokenPrice aggregates the USDT price of a non-pegged payment token.
+func (rp *RatePipeline) TokenPrice(ctx context.Context, pair RatePair) (*AggregatedRate, error) {
+	aggregator, exists := rp.tokenPrices[pair]
+	if !exists {
+		return nil, fmt.Errorf("no providers configured for %s", pair)
+	}
+	return aggregator.Aggregate(ctx, pair)
+}
+
+func (rp *RatePipeline) TokenPairs() []RatePair {
+	pairs := make([]RatePair, 0, len(rp.tokenPrices))
+	for pair := range rp.tokenPrices {
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// applySpread lowers the IDR-per-USDT rate so the customer pays spreadPercent
+// more USDT than the market rate would require.
+func applySpread(marketRate, spreadPercent float64) float64 {
+	rate := marketRate * (1 - spreadPercent/100)
+	return math.Round(rate*1000000) / 1000000
+}
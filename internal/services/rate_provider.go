@@ -0,0 +1,476 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"sermorpheus-engine-test/internal/config"
+)
+
+type RatePair string
+
+const (
+	PairUSDIDR  RatePair = "USD/IDR"
+	PairUSDTUSD RatePair = "USDT/USD"
+	PairUSDTIDR RatePair = "USDT/IDR"
+	PairBNBUSDT RatePair = "BNB/USDT"
+	PairETHUSDT RatePair = "ETH/USDT"
+	PairPOLUSDT RatePair = "POL/USDT"
+)
+
+// nativeCoinPairs maps a chain's native coin to the pair it is priced by.
+var nativeCoinPairs = map[string]RatePair{
+	"BNB": PairBNBUSDT,
+	"ETH": PairETHUSDT,
+	"POL": PairPOLUSDT,
+}
+
+type RateQuote struct {
+	Source    string    `json:"source"`
+	Pair      RatePair  `json:"pair"`
+	Rate      float64   `json:"rate"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type RateProvider interface {
+	Name() string
+	FetchRate(ctx context.Context, pair RatePair) (*RateQuote, error)
+}
+
+var rateHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+func errUnsupportedPair(provider string, pair RatePair) error {
+	return fmt.Errorf("%s does not provide %s", provider, pair)
+}
+
+type ExchangeRateAPIProvider struct {
+	url string
+}
+
+func NewExchangeRateAPIProvider() *ExchangeRateAPIProvider {
+	return &ExchangeRateAPIProvider{url: "https://api.exchangerate-api.com/v4/latest/USD"}
+}
+
+func (p *ExchangeRateAPIProvider) Name() string {
+	return "exchangerate-api"
+}
+
+func (p *ExchangeRateAPIProvider) FetchRate(ctx context.Context, pair RatePair) (*RateQuote, error) {
+	if pair != PairUSDIDR {
+		return nil, errUnsupportedPair(p.Name(), pair)
+	}
+
+	var resp struct {
+		Rates           map[string]float64 `json:"rates"`
+		TimeLastUpdated int64              `json:"time_last_updated"`
+	}
+	if err := getJSON(ctx, p.url, &resp); err != nil {
+		return nil, err
+	}
+
+	idrRate, exists := resp.Rates["IDR"]
+	if !exists {
+		return nil, errors.New("IDR rate not found in response")
+	}
+
+	return &RateQuote{Source: p.Name(), Pair: pair, Rate: idrRate, Timestamp: time.Unix(resp.TimeLastUpdated, 0)}, nil
+}
+
+type OpenERAPIProvider struct {
+	url string
+}
+
+func NewOpenERAPIProvider() *OpenERAPIProvider {
+	return &OpenERAPIProvider{url: "https://open.er-api.com/v6/latest/USD"}
+}
+
+func (p *OpenERAPIProvider) Name() string {
+	return "open-er-api"
+}
+
+func (p *OpenERAPIProvider) FetchRate(ctx context.Context, pair RatePair) (*RateQuote, error) {
+	if pair != PairUSDIDR {
+		return nil, errUnsupportedPair(p.Name(), pair)
+	}
+
+	var resp struct {
+		Result             string             `json:"result"`
+		Rates              map[string]float64 `json:"rates"`
+		TimeLastUpdateUnix int64              `json:"time_last_update_unix"`
+	}
+	if err := getJSON(ctx, p.url, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Result != "success" {
+		return nil, fmt.Errorf("unexpected result %q", resp.Result)
+	}
+
+	idrRate, exists := resp.Rates["IDR"]
+	if !exists {
+		return nil, errors.New("IDR rate not found in response")
+	}
+
+	return &RateQuote{Source: p.Name(), Pair: pair, Rate: idrRate, Timestamp: time.Unix(resp.TimeLastUpdateUnix, 0)}, nil
+}
+
+type FrankfurterProvider struct {
+	url string
+}
+
+func NewFrankfurterProvider() *FrankfurterProvider {
+	return &FrankfurterProvider{url: "https://api.frankfurter.app/latest?from=USD&to=IDR"}
+}
+
+func (p *FrankfurterProvider) Name() string {
+	return "frankfurter"
+}
+
+func (p *FrankfurterProvider) FetchRate(ctx context.Context, pair RatePair) (*RateQuote, error) {
+	if pair != PairUSDIDR {
+		return nil, errUnsupportedPair(p.Name(), pair)
+	}
+
+	var resp struct {
+		Date  string             `json:"date"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := getJSON(ctx, p.url, &resp); err != nil {
+		return nil, err
+	}
+
+	idrRate, exists := resp.Rates["IDR"]
+	if !exists {
+		return nil, errors.New("IDR rate not found in response")
+	}
+
+	date, err := time.Parse("2006-01-02", resp.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate date %q: %w", resp.Date, err)
+	}
+
+	return &RateQuote{Source: p.Name(), Pair: pair, Rate: idrRate, Timestamp: date}, nil
+}
+
+type CoinGeckoProvider struct {
+	baseURL string
+}
+
+func NewCoinGeckoProvider() *CoinGeckoProvider {
+	return &CoinGeckoProvider{baseURL: "https://api.coingecko.com/api/v3/simple/price"}
+}
+
+func (p *CoinGeckoProvider) Name() string {
+	return "coingecko"
+}
+
+func (p *CoinGeckoProvider) FetchRate(ctx context.Context, pair RatePair) (*RateQuote, error) {
+	var currency string
+	switch pair {
+	case PairUSDTUSD:
+		currency = "usd"
+	case PairUSDTIDR:
+		currency = "idr"
+	case PairBNBUSDT, PairETHUSDT, PairPOLUSDT:
+		return p.fetchCoinUSDT(ctx, pair)
+	default:
+		return nil, errUnsupportedPair(p.Name(), pair)
+	}
+
+	var resp map[string]map[string]float64
+	url := fmt.Sprintf("%s?ids=tether&vs_currencies=%s&include_last_updated_at=true", p.baseURL, currency)
+	if err := getJSON(ctx, url, &resp); err != nil {
+		return nil, err
+	}
+
+	tether, exists := resp["tether"]
+	if !exists {
+		return nil, errors.New("tether price not found in response")
+	}
+	price, exists := tether[currency]
+	if !exists {
+		return nil, fmt.Errorf("tether %s price not found in response", currency)
+	}
+
+	return &RateQuote{Source: p.Name(), Pair: pair, Rate: price, Timestamp: time.Unix(int64(tether["last_updated_at"]), 0)}, nil
+}
+
+var coinGeckoCoinIDs = map[RatePair]string{
+	PairBNBUSDT: "binancecoin",
+	PairETHUSDT: "ethereum",
+	PairPOLUSDT: "polygon-ecosystem-token",
+}
+
+// fetchCoinUSDT derives a coin's USDT price from its and USDT's prices in USD,
+// since CoinGecko does not quote against USDT directly.
+func (p *CoinGeckoProvider) fetchCoinUSDT(ctx context.Context, pair RatePair) (*RateQuote, error) {
+	id := coinGeckoCoinIDs[pair]
+
+	var resp map[string]map[string]float64
+	url := fmt.Sprintf("%s?ids=%s,tether&vs_currencies=usd&include_last_updated_at=true", p.baseURL, id)
+	if err := getJSON(ctx, url, &resp); err != nil {
+		return nil, err
+	}
+
+	coin, tether := resp[id], resp["tether"]
+	if coin["usd"] <= 0 || tether["usd"] <= 0 {
+		return nil, fmt.Errorf("%s or tether price not found in response", id)
+	}
+
+	updatedAt := math.Min(coin["last_updated_at"], tether["last_updated_at"])
+	return &RateQuote{Source: p.Name(), Pair: pair, Rate: coin["usd"] / tether["usd"], Timestamp: time.Unix(int64(updatedAt), 0)}, nil
+}
+
+type BinanceProvider struct {
+	baseURL string
+}
+
+var binanceSymbols = map[RatePair]string{
+	PairBNBUSDT: "BNBUSDT",
+	PairETHUSDT: "ETHUSDT",
+	PairPOLUSDT: "POLUSDT",
+}
+
+func NewBinanceProvider() *BinanceProvider {
+	return &BinanceProvider{baseURL: "https://api.binance.com/api/v3/ticker/price"}
+}
+
+func (p *BinanceProvider) Name() string {
+	return "binance"
+}
+
+func (p *BinanceProvider) FetchRate(ctx context.Context, pair RatePair) (*RateQuote, error) {
+	symbol, exists := binanceSymbols[pair]
+	if !exists {
+		return nil, errUnsupportedPair(p.Name(), pair)
+	}
+
+	var resp struct {
+		Symbol string `json:"symbol"`
+		Price  string `json:"price"`
+	}
+	if err := getJSON(ctx, fmt.Sprintf("%s?symbol=%s", p.baseURL, symbol), &resp); err != nil {
+		return nil, err
+	}
+
+	price, err := strconv.ParseFloat(resp.Price, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid binance price: %w", err)
+	}
+
+	return &RateQuote{Source: p.Name(), Pair: pair, Rate: price, Timestamp: time.Now()}, nil
+}
+
+type KrakenProvider struct {
+	url string
+}
+
+func NewKrakenProvider() *KrakenProvider {
+	return &KrakenProvider{url: "https://api.kraken.com/0/public/Ticker?pair=USDTZUSD"}
+}
+
+func (p *KrakenProvider) Name() string {
+	return "kraken"
+}
+
+func (p *KrakenProvider) FetchRate(ctx context.Context, pair RatePair) (*RateQuote, error) {
+	if pair != PairUSDTUSD {
+		return nil, errUnsupportedPair(p.Name(), pair)
+	}
+
+	var resp struct {
+		Error  []string `json:"error"`
+		Result map[string]struct {
+			LastTrade []string `json:"c"`
+		} `json:"result"`
+	}
+	if err := getJSON(ctx, p.url, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Error) > 0 {
+		return nil, fmt.Errorf("kraken error: %s", strings.Join(resp.Error, ", "))
+	}
+
+	for _, ticker := range resp.Result {
+		if len(ticker.LastTrade) == 0 {
+			break
+		}
+		price, err := strconv.ParseFloat(ticker.LastTrade[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kraken price: %w", err)
+		}
+		return &RateQuote{Source: p.Name(), Pair: pair, Rate: price, Timestamp: time.Now()}, nil
+	}
+
+	return nil, errors.New("USDT/USD ticker not found in response")
+}
+
+type IndodaxProvider struct {
+	url string
+}
+
+func NewIndodaxProvider() *IndodaxProvider {
+	return &IndodaxProvider{url: "https://indodax.com/api/ticker/usdtidr"}
+}
+
+func (p *IndodaxProvider) Name() string {
+	return "indodax"
+}
+
+func (p *IndodaxProvider) FetchRate(ctx context.Context, pair RatePair) (*RateQuote, error) {
+	if pair != PairUSDTIDR {
+		return nil, errUnsupportedPair(p.Name(), pair)
+	}
+
+	var resp struct {
+		Ticker struct {
+			Last       string `json:"last"`
+			ServerTime int64  `json:"server_time"`
+		} `json:"ticker"`
+	}
+	if err := getJSON(ctx, p.url, &resp); err != nil {
+		return nil, err
+	}
+
+	price, err := strconv.ParseFloat(resp.Ticker.Last, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid indodax price: %w", err)
+	}
+
+	return &RateQuote{Source: p.Name(), Pair: pair, Rate: price, Timestamp: time.Unix(resp.Ticker.ServerTime, 0)}, nil
+}
+
+type StaticRateProvider struct {
+	rates map[RatePair]float64
+}
+
+func NewStaticRateProvider(rates map[RatePair]float64) *StaticRateProvider {
+	return &StaticRateProvider{rates: rates}
+}
+
+func (p *StaticRateProvider) Name() string {
+	return "static"
+}
+
+func (p *StaticRateProvider) FetchRate(ctx context.Context, pair RatePair) (*RateQuote, error) {
+	rate := p.rates[pair]
+	if rate <= 0 {
+		return nil, fmt.Errorf("static %s rate is not configured", pair)
+	}
+	return &RateQuote{Source: p.Name(), Pair: pair, Rate: rate, Timestamp: time.Now()}, nil
+}
+
+type FileRateProvider struct {
+	path string
+}
+
+func NewFileRateProvider(path string) *FileRateProvider {
+	return &FileRateProvider{path: path}
+}
+
+func (p *FileRateProvider) Name() string {
+	return "file"
+}
+
+func (p *FileRateProvider) FetchRate(ctx context.Context, pair RatePair) (*RateQuote, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate file: %w", err)
+	}
+
+	var content struct {
+		Rates     map[RatePair]float64 `json:"rates"`
+		Timestamp time.Time            `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to decode rate file: %w", err)
+	}
+
+	rate, exists := content.Rates[pair]
+	if !exists {
+		return nil, errUnsupportedPair(p.Name(), pair)
+	}
+
+	timestamp := content.Timestamp
+	if timestamp.IsZero() {
+		info, err := os.Stat(p.path)
+		if err != nil {
+			return nil, err
+		}
+		timestamp = info.ModTime()
+	}
+
+	return &RateQuote{Source: p.Name(), Pair: pair, Rate: rate, Timestamp: timestamp}, nil
+}
+
+func BuildRateProviders(cfg *config.Config, names []string) ([]RateProvider, error) {
+	var providers []RateProvider
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "exchangerate-api":
+			providers = append(providers, NewExchangeRateAPIProvider())
+		case "open-er-api":
+			providers = append(providers, NewOpenERAPIProvider())
+		case "frankfurter":
+			providers = append(providers, NewFrankfurterProvider())
+		case "coingecko":
+			providers = append(providers, NewCoinGeckoProvider())
+		case "kraken":
+			providers = append(providers, NewKrakenProvider())
+		case "indodax":
+			providers = append(providers, NewIndodaxProvider())
+		case "binance":
+			providers = append(providers, NewBinanceProvider())
+		case "static":
+			providers = append(providers, NewStaticRateProvider(map[RatePair]float64{
+				PairUSDIDR:  cfg.RateStaticUSDIDR,
+				PairUSDTUSD: cfg.RateStaticUSDTUSD,
+				PairUSDTIDR: cfg.RateStaticUSDTIDR,
+				PairBNBUSDT: cfg.RateStaticBNBUSDT,
+				PairETHUSDT: cfg.RateStaticETHUSDT,
+				PairPOLUSDT: cfg.RateStaticPOLUSDT,
+			}))
+		case "file":
+			providers = append(providers, NewFileRateProvider(cfg.RateFilePath))
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown rate provider %q", name)
+		}
+	}
+
+	if len(providers) == 0 {
+		return nil, errors.New("no rate providers configured")
+	}
+	return providers, nil
+}
+
+func getJSON(ctx context.Context, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := rateHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch exchange rate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("exchange rate API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode exchange rate response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sermorpheus-engine-test/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TokenPrice struct {
+	Pair      RatePair  `json:"pair"`
+	Price     float64   `json:"price"`
+	Providers string    `json:"providers"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TokenRate prices one unit of a payment token in IDR on top of the current
+// USDT rate.
+type TokenRate struct {
+	Symbol       string     `json:"symbol"`
+	RateID       uuid.UUID  `json:"rate_id"`
+	USDTRate     money.Rate `json:"usdt_rate"`
+	USDTPerToken float64    `json:"usdt_per_token"`
+	IDRPerToken  money.Rate `json:"idr_per_token"`
+}
+
+func (rs *RateService) GetTokenRate(token *PaymentToken) (*TokenRate, error) {
+	rate, err := rs.GetCurrentRate()
+	if err != nil {
+		return nil, err
+	}
+
+	usdtRate := money.RateFromFloat(rate.IDRToUSDTRate)
+	usdtPerToken := 1.0
+	idrPerToken := usdtRate
+	if token.RatePair != "" {
+		rs.mu.RLock()
+		price := rs.tokenPrices[token.RatePair]
+		rs.mu.RUnlock()
+
+		if price == nil {
+			return nil, fmt.Errorf("%w: no %s price cached", ErrNoTrustworthyRate, token.RatePair)
+		}
+		if time.Since(price.UpdatedAt) > rs.maxAge {
+			return nil, fmt.Errorf("%w: cached %s price from %s is stale", ErrNoTrustworthyRate, token.RatePair, price.UpdatedAt.Format(time.RFC3339))
+		}
+		usdtPerToken = price.Price
+		idrPerToken = money.RateFromFloat(rate.IDRToUSDTRate * usdtPerToken)
+	}
+
+	return &TokenRate{
+		Symbol:       token.Symbol,
+		RateID:       rate.ID,
+		USDTRate:     usdtRate,
+		USDTPerToken: usdtPerToken,
+		IDRPerToken:  idrPerToken,
+	}, nil
+}
+
+func (rs *RateService) refreshTokenPrices(ctx context.Context) {
+	for _, pair := range rs.pipeline.TokenPairs() {
+		aggregated, err := rs.pipeline.TokenPrice(ctx, pair)
+		if err != nil {
+			log.Printf("Failed to refresh %s price: %v", pair, err)
+			continue
+		}
+
+		rs.mu.Lock()
+		rs.tokenPrices[pair] = &TokenPrice{
+			Pair:      pair,
+			Price:     aggregated.Rate,
+			Providers: aggregated.Sources(),
+			UpdatedAt: time.Now(),
+		}
+		rs.mu.Unlock()
+	}
+}
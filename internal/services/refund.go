@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"sermorpheus-engine-test/internal/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRefundCompleted = errors.New("refund has already been completed")
+	ErrInvalidTxHash   = errors.New("tx_hash must be a 0x-prefixed 32-byte hex transaction hash")
+)
+
+var txHashPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+
+// RefundService tracks refunds owed for cancelled events. The engine holds
+// no keys, so refunds are sent from the treasury by an operator, who then
+// records the on-chain transaction here. Only then is the transaction
+// marked refunded and the customer told.
+type RefundService struct {
+	db *gorm.DB
+}
+
+type CompleteRefundRequest struct {
+	TxHash string `json:"tx_hash" binding:"required"`
+}
+
+func NewRefundService(db *gorm.DB) *RefundService {
+	return &RefundService{db: db}
+}
+
+func (rs *RefundService) ListRefunds(status string, limit int) ([]models.Refund, error) {
+	query := rs.db.Model(&models.Refund{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var refunds []models.Refund
+	if err := query.Order("created_at DESC").Limit(limit).Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// CompleteRefund records the transaction that sent a refund, moves its
+// booking from refund_pending to refunded and announces the refund.
+func (rs *RefundService) CompleteRefund(id uuid.UUID, req *CompleteRefundRequest) (*models.Refund, error) {
+	txHash := strings.TrimSpace(req.TxHash)
+	if !txHashPattern.MatchString(txHash) {
+		return nil, ErrInvalidTxHash
+	}
+
+	var refund models.Refund
+	err := rs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&refund, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if refund.Status != "pending" {
+			return ErrRefundCompleted
+		}
+
+		now := time.Now()
+		if err := tx.Model(&refund).Updates(map[string]interface{}{
+			"status":      "completed",
+			"tx_hash":     txHash,
+			"refunded_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		refund.Status = "completed"
+		refund.TxHash = txHash
+		refund.RefundedAt = &now
+
+		if err := tx.Model(&models.Transaction{}).
+			Where("id = ? AND status = ?", refund.TransactionID, "refund_pending").
+			Update("status", "refunded").Error; err != nil {
+			return err
+		}
+		return recordTransactionEvent(tx, TransactionRefunded, refund.TransactionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
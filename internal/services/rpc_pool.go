@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// lagPenalty is how much latency one block of height lag is worth when
+// ranking endpoints.
+const lagPenalty = 500 * time.Millisecond
+
+var ErrNoHealthyEndpoint = errors.New("no healthy RPC endpoint available")
+
+type rpcEndpoint struct {
+	url         string
+	client      *ethclient.Client
+	healthy     bool
+	latency     time.Duration
+	blockHeight uint64
+	lag         uint64
+	failures    int
+	lastError   string
+	lastChecked time.Time
+}
+
+type RPCEndpointStatus struct {
+	URL         string    `json:"url"`
+	Connected   bool      `json:"connected"`
+	Healthy     bool      `json:"healthy"`
+	LatencyMs   int64     `json:"latency_ms"`
+	BlockHeight uint64    `json:"block_height"`
+	BlockLag    uint64    `json:"block_lag"`
+	Score       int64     `json:"score"`
+	Failures    int       `json:"failures"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastChecked time.Time `json:"last_checked"`
+}
+
+// RPCPool spreads calls for one chain over several RPC endpoints. Endpoints
+// are dialled lazily, health-checked in the background, ranked by latency and
+// block-height lag, and a failing call fails over to the next endpoint.
+type RPCPool struct {
+	chainName string
+	timeout   time.Duration
+	maxLag    uint64
+
+	mu        sync.RWMutex
+	endpoints []*rpcEndpoint
+}
+
+func NewRPCPool(chainName string, urls []string, timeout time.Duration, maxLag uint64) *RPCPool {
+	pool := &RPCPool{chainName: chainName, timeout: timeout, maxLag: maxLag}
+	for _, url := range urls {
+		// Endpoints start out healthy so calls are attempted before the
+		// first health check has run.
+		pool.endpoints = append(pool.endpoints, &rpcEndpoint{url: url, healthy: true})
+	}
+	return pool
+}
+
+func (p *RPCPool) StartHealthChecks(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			p.CheckHealth()
+			<-ticker.C
+		}
+	}()
+}
+
+func (p *RPCPool) CheckHealth() {
+	type result struct {
+		height  uint64
+		latency time.Duration
+		err     error
+	}
+
+	p.mu.RLock()
+	endpoints := append([]*rpcEndpoint(nil), p.endpoints...)
+	p.mu.RUnlock()
+
+	results := make([]result, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint *rpcEndpoint) {
+			defer wg.Done()
+
+			client, err := p.clientFor(endpoint)
+			if err != nil {
+				results[i] = result{err: err}
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+			defer cancel()
+
+			start := time.Now()
+			height, err := client.BlockNumber(ctx)
+			results[i] = result{height: height, latency: time.Since(start), err: err}
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	var best uint64
+	for _, r := range results {
+		if r.err == nil && r.height > best {
+			best = r.height
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for i, endpoint := range endpoints {
+		r := results[i]
+		endpoint.lastChecked = now
+
+		if r.err != nil {
+			wasHealthy := endpoint.healthy
+			endpoint.healthy = false
+			endpoint.failures++
+			endpoint.lastError = r.err.Error()
+			if wasHealthy {
+				log.Printf("RPC endpoint %s for %s is unhealthy: %v", endpoint.url, p.chainName, r.err)
+			}
+			continue
+		}
+
+		endpoint.latency = r.latency
+		endpoint.blockHeight = r.height
+		endpoint.lag = best - r.height
+
+		healthy := p.maxLag == 0 || endpoint.lag <= p.maxLag
+		if healthy && !endpoint.healthy {
+			log.Printf("RPC endpoint %s for %s recovered", endpoint.url, p.chainName)
+		} else if !healthy && endpoint.healthy {
+			log.Printf("RPC endpoint %s for %s is %d blocks behind", endpoint.url, p.chainName, endpoint.lag)
+		}
+
+		endpoint.healthy = healthy
+		if healthy {
+			endpoint.failures = 0
+			endpoint.lastError = ""
+		} else {
+			endpoint.lastError = fmt.Sprintf("%d blocks behind", endpoint.lag)
+		}
+	}
+}
+
+func (p *RPCPool) Status() []RPCEndpointStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	statuses := make([]RPCEndpointStatus, 0, len(p.endpoints))
+	for _, endpoint := range p.endpoints {
+		statuses = append(statuses, RPCEndpointStatus{
+			URL:         endpoint.url,
+			Connected:   endpoint.client != nil,
+			Healthy:     endpoint.healthy,
+			LatencyMs:   endpoint.latency.Milliseconds(),
+			BlockHeight: endpoint.blockHeight,
+			BlockLag:    endpoint.lag,
+			Score:       endpoint.score().Milliseconds(),
+			Failures:    endpoint.failures,
+			LastError:   endpoint.lastError,
+			LastChecked: endpoint.lastChecked,
+		})
+	}
+	return statuses
+}
+
+func (p *RPCPool) BlockNumber(ctx context.Context) (uint64, error) {
+	var height uint64
+	err := p.call(ctx, func(ctx context.Context, client *ethclient.Client) error {
+		var err error
+		height, err = client.BlockNumber(ctx)
+		return err
+	})
+	return height, err
+}
+
+func (p *RPCPool) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	var block *types.Block
+	err := p.call(ctx, func(ctx context.Context, client *ethclient.Client) error {
+		var err error
+		block, err = client.BlockByNumber(ctx, number)
+		return err
+	})
+	return block, err
+}
+
+func (p *RPCPool) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	err := p.call(ctx, func(ctx context.Context, client *ethclient.Client) error {
+		var err error
+		receipt, err = client.TransactionReceipt(ctx, hash)
+		return err
+	})
+	return receipt, err
+}
+
+// call runs fn against the best-ranked endpoints in turn until one succeeds.
+// Unhealthy endpoints are only tried once every healthy one has failed.
+func (p *RPCPool) call(ctx context.Context, fn func(context.Context, *ethclient.Client) error) error {
+	var lastErr error
+	for _, endpoint := range p.ranked() {
+		client, err := p.clientFor(endpoint)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		err = fn(callCtx, client)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ethereum.NotFound) {
+			return err
+		}
+
+		p.markFailure(endpoint, err)
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		return ErrNoHealthyEndpoint
+	}
+	return fmt.Errorf("%w: %v", ErrNoHealthyEndpoint, lastErr)
+}
+
+func (p *RPCPool) ranked() []*rpcEndpoint {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ranked := append([]*rpcEndpoint(nil), p.endpoints...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].healthy != ranked[j].healthy {
+			return ranked[i].healthy
+		}
+		return ranked[i].score() < ranked[j].score()
+	})
+	return ranked
+}
+
+// clientFor returns the endpoint's client, dialling it on first use so an
+// endpoint that was down at startup is picked up once it comes back.
+func (p *RPCPool) clientFor(endpoint *rpcEndpoint) (*ethclient.Client, error) {
+	p.mu.RLock()
+	client := endpoint.client
+	p.mu.RUnlock()
+	if client != nil {
+		return client, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, endpoint.url)
+	if err != nil {
+		p.markFailure(endpoint, err)
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if endpoint.client != nil {
+		client.Close()
+		return endpoint.client, nil
+	}
+	endpoint.client = client
+	return client, nil
+}
+
+func (p *RPCPool) markFailure(endpoint *rpcEndpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if endpoint.healthy {
+		log.Printf("RPC endpoint %s for %s failed, failing over: %v", endpoint.url, p.chainName, err)
+	}
+	endpoint.healthy = false
+	endpoint.failures++
+	endpoint.lastError = err.Error()
+}
+
+func (e *rpcEndpoint) score() time.Duration {
+	return e.latency + time.Duration(e.lag)*lagPenalty
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"fmt"
+	"sermorpheus-engine-test/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SeatService struct {
+	db *gorm.DB
+}
+
+type SeatingLayout struct {
+	Sections []SeatingSection `json:"sections"`
+}
+
+type SeatingSection struct {
+	Name string       `json:"name"`
+	Rows []SeatingRow `json:"rows"`
+}
+
+type SeatingRow struct {
+	Label string `json:"label"`
+	Seats int    `json:"seats"`
+}
+
+func NewSeatService(db *gorm.DB) *SeatService {
+	return &SeatService{db: db}
+}
+
+func (ss *SeatService) ConfigureLayout(eventID uuid.UUID, layout *SeatingLayout) ([]models.Seat, error) {
+	seats, err := layoutSeats(eventID, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ss.db.Transaction(func(tx *gorm.DB) error {
+		var event models.Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&event, "id = ?", eventID).Error; err != nil {
+			return err
+		}
+
+		if event.Status == "cancelled" {
+			return invalid("cancelled events cannot be updated")
+		}
+
+		var activeTransactions int64
+		if err := tx.Model(&models.Transaction{}).
+			Where("event_id = ? AND status IN ?", eventID, []string{"pending", "paid"}).
+			Count(&activeTransactions).Error; err != nil {
+			return err
+		}
+		if event.AvailableQuota != event.Quota || activeTransactions > 0 {
+			return invalid("seating layout cannot be changed after tickets are sold")
+		}
+
+		if err := tx.Where("event_id = ?", eventID).Delete(&models.Seat{}).Error; err != nil {
+			return err
+		}
+		if err := tx.CreateInBatches(&seats, 500).Error; err != nil {
+			return err
+		}
+
+		event.HasSeating = true
+		event.Quota = len(seats)
+		event.AvailableQuota = len(seats)
+		return tx.Save(&event).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return seats, nil
+}
+
+// layoutSeats validates a seating layout and lists its seats. Section names
+// and the row labels within a section must be unique, as each seat's
+// position is.
+func layoutSeats(eventID uuid.UUID, layout *SeatingLayout) ([]models.Seat, error) {
+	var seats []models.Seat
+	sections := make(map[string]bool)
+	for _, section := range layout.Sections {
+		if section.Name == "" {
+			return nil, invalid("section name is required")
+		}
+		if sections[section.Name] {
+			return nil, invalid(fmt.Sprintf("section %s appears more than once", section.Name))
+		}
+		sections[section.Name] = true
+
+		rows := make(map[string]bool)
+		for _, row := range section.Rows {
+			if row.Label == "" || row.Seats <= 0 {
+				return nil, invalid(fmt.Sprintf("invalid row in section %s", section.Name))
+			}
+			if rows[row.Label] {
+				return nil, invalid(fmt.Sprintf("row %s appears more than once in section %s", row.Label, section.Name))
+			}
+			rows[row.Label] = true
+
+			for number := 1; number <= row.Seats; number++ {
+				seats = append(seats, models.Seat{
+					EventID: eventID,
+					Section: section.Name,
+					Row:     row.Label,
+					Number:  number,
+					Status:  "available",
+				})
+			}
+		}
+	}
+
+	if len(seats) == 0 {
+		return nil, invalid("seating layout must contain at least one seat")
+	}
+	return seats, nil
+}
+
+func (ss *SeatService) GetSeats(eventID uuid.UUID) ([]models.Seat, error) {
+	var seats []models.Seat
+	if err := ss.db.Where("event_id = ?", eventID).
+		Order("section, row, number").
+		Find(&seats).Error; err != nil {
+		return nil, err
+	}
+	return seats, nil
+}
+
+func (ss *SeatService) HoldSeats(tx *gorm.DB, eventID, transactionID uuid.UUID, seatIDs []uuid.UUID, until time.Time) ([]models.Seat, error) {
+	var seats []models.Seat
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ? AND event_id = ?", seatIDs, eventID).
+		Order("section, row, number").
+		Find(&seats).Error; err != nil {
+		return nil, err
+	}
+
+	if len(seats) != len(seatIDs) {
+		return nil, ErrSeatNotFound
+	}
+
+	for _, seat := range seats {
+		if seat.Status != "available" {
+			return nil, ErrSeatUnavailable
+		}
+	}
+
+	result := tx.Model(&models.Seat{}).
+		Where("id IN ? AND status = ?", seatIDs, "available").
+		Updates(map[string]interface{}{
+			"status":         "held",
+			"transaction_id": transactionID,
+			"held_until":     until,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != int64(len(seatIDs)) {
+		return nil, ErrSeatUnavailable
+	}
+
+	return seats, nil
+}
+
+func markSeatsSold(tx *gorm.DB, transactionID uuid.UUID) error {
+	return tx.Model(&models.Seat{}).
+		Where("transaction_id = ? AND status = ?", transactionID, "held").
+		Updates(map[string]interface{}{
+			"status":     "sold",
+			"held_until": nil,
+		}).Error
+}
+
+func releaseSeats(tx *gorm.DB, transactionID uuid.UUID) error {
+	return tx.Model(&models.Seat{}).
+		Where("transaction_id = ?", transactionID).
+		Updates(map[string]interface{}{
+			"status":         "available",
+			"transaction_id": nil,
+			"held_until":     nil,
+		}).Error
+}
+
+func seatLabel(seat *models.Seat) string {
+	return fmt.Sprintf("%s-%s-%d", seat.Section, seat.Row, seat.Number)
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestLayoutSeats(t *testing.T) {
+	eventID := uuid.New()
+	tests := []struct {
+		name      string
+		layout    SeatingLayout
+		wantSeats int
+		wantErr   string
+	}{
+		{
+			name: "valid",
+			layout: SeatingLayout{Sections: []SeatingSection{
+				{Name: "A", Rows: []SeatingRow{{Label: "1", Seats: 10}, {Label: "2", Seats: 8}}},
+				{Name: "B", Rows: []SeatingRow{{Label: "1", Seats: 5}}},
+			}},
+			wantSeats: 23,
+		},
+		{
+			name:    "empty",
+			layout:  SeatingLayout{},
+			wantErr: "seating layout must contain at least one seat",
+		},
+		{
+			name:    "unnamed section",
+			layout:  SeatingLayout{Sections: []SeatingSection{{Rows: []SeatingRow{{Label: "1", Seats: 1}}}}},
+			wantErr: "section name is required",
+		},
+		{
+			name:    "row without seats",
+			layout:  SeatingLayout{Sections: []SeatingSection{{Name: "A", Rows: []SeatingRow{{Label: "1"}}}}},
+			wantErr: "invalid row in section A",
+		},
+		{
+			name: "duplicate section",
+			layout: SeatingLayout{Sections: []SeatingSection{
+				{Name: "A", Rows: []SeatingRow{{Label: "1", Seats: 1}}},
+				{Name: "A", Rows: []SeatingRow{{Label: "2", Seats: 1}}},
+			}},
+			wantErr: "section A appears more than once",
+		},
+		{
+			name: "duplicate row",
+			layout: SeatingLayout{Sections: []SeatingSection{
+				{Name: "A", Rows: []SeatingRow{{Label: "1", Seats: 1}, {Label: "1", Seats: 2}}},
+			}},
+			wantErr: "row 1 appears more than once in section A",
+		},
+	}
+
+	for _, tt := range tests {
+		seats, err := layoutSeats(eventID, &tt.layout)
+		if tt.wantErr != "" {
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Message != tt.wantErr {
+				t.Errorf("%s: layoutSeats error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: layoutSeats returned error: %v", tt.name, err)
+			continue
+		}
+		if len(seats) != tt.wantSeats {
+			t.Errorf("%s: got %d seats, want %d", tt.name, len(seats), tt.wantSeats)
+		}
+	}
+}
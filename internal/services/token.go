@@ -0,0 +1,45 @@
+package services
+
+import (
+	"sermorpheus-engine-test/internal/money"
+)
+
+// TokenRateSourcePeg marks stablecoins that are priced 1:1 against USDT.
+const TokenRateSourcePeg = "peg"
+
+// PaymentToken is a token accepted on one chain. The same symbol on two
+// chains is two payment tokens with their own contract and decimals.
+type PaymentToken struct {
+	Symbol     string   `json:"symbol"`
+	Name       string   `json:"name"`
+	ChainID    int64    `json:"chain_id"`
+	Contract   string   `json:"contract,omitempty"`
+	Decimals   int      `json:"decimals"`
+	RateSource string   `json:"rate_source"`
+	RatePair   RatePair `json:"-"`
+}
+
+// IsNative reports whether the token is the chain's native coin, which is
+// paid with a plain value transfer instead of an ERC-20 Transfer.
+func (t *PaymentToken) IsNative() bool {
+	return t.Contract == ""
+}
+
+var tokenNames = map[string]string{
+	"USDT": "Tether USD",
+	"USDC": "USD Coin",
+	"BUSD": "Binance USD",
+	"BNB":  "BNB",
+	"ETH":  "Ether",
+	"POL":  "POL",
+}
+
+// maxTokenPlaces caps the precision of payment amounts so they stay typeable
+// in a wallet.
+const maxTokenPlaces = 8
+
+// calculateTokenAmount converts an IDR amount into base units of the payment
+// token, rounded to at most maxTokenPlaces decimals.
+func calculateTokenAmount(amount money.IDR, idrPerToken money.Rate, decimals int) money.Units {
+	return money.ToUnits(amount, idrPerToken, decimals).Round(decimals, maxTokenPlaces)
+}
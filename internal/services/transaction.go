@@ -4,8 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math"
 	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,7 +17,18 @@ type TransactionService struct {
 	eventService      *EventService
 	rateService       *RateService
 	blockchainService *BlockchainService
-	platformFee       float64
+	seatService       *SeatService
+	waitlistService   *WaitlistService
+	quoteService      *QuoteService
+	chains            *ChainRegistry
+	uniqueAmounts     *UniqueAmountAllocator
+	feeService        *FeeService
+	promoService      *PromoService
+
+	// paymentWindow is how long a new order waits for payment; reminders
+	// are sent that long before its deadline, longest first.
+	paymentWindow time.Duration
+	reminders     []time.Duration
 }
 
 func NewTransactionService(
@@ -25,21 +36,44 @@ func NewTransactionService(
 	eventService *EventService,
 	rateService *RateService,
 	blockchainService *BlockchainService,
-	platformFeePercent float64,
+	seatService *SeatService,
+	waitlistService *WaitlistService,
+	quoteService *QuoteService,
+	chains *ChainRegistry,
+	uniqueAmounts *UniqueAmountAllocator,
+	feeService *FeeService,
+	promoService *PromoService,
+	paymentWindow time.Duration,
+	reminders []time.Duration,
 ) *TransactionService {
 	return &TransactionService{
 		db:                db,
 		eventService:      eventService,
 		rateService:       rateService,
 		blockchainService: blockchainService,
-		platformFee:       platformFeePercent,
+		seatService:       seatService,
+		waitlistService:   waitlistService,
+		quoteService:      quoteService,
+		chains:            chains,
+		uniqueAmounts:     uniqueAmounts,
+		feeService:        feeService,
+		promoService:      promoService,
+		paymentWindow:     paymentWindow,
+		reminders:         reminders,
 	}
 }
 
 type CreateTransactionRequest struct {
-	CustomerID uuid.UUID `json:"customer_id"`
-	EventID    uuid.UUID `json:"event_id"`
-	Quantity   int       `json:"quantity"`
+	CustomerID uuid.UUID   `json:"customer_id"`
+	EventID    uuid.UUID   `json:"event_id"`
+	Quantity   int         `json:"quantity"`
+	SeatIDs    []uuid.UUID `json:"seat_ids,omitempty"`
+	ChainID    int64       `json:"chain_id,omitempty"`
+	Token      string      `json:"token,omitempty"`
+
+	WaitlistEntryID *uuid.UUID `json:"waitlist_entry_id,omitempty"`
+	QuoteID         *uuid.UUID `json:"quote_id,omitempty"`
+	PromoCode       string     `json:"promo_code,omitempty"`
 }
 
 func (ts *TransactionService) CreateTransaction(req *CreateTransactionRequest) (*models.Transaction, error) {
@@ -48,57 +82,182 @@ func (ts *TransactionService) CreateTransaction(req *CreateTransactionRequest) (
 	err := ts.db.Transaction(func(tx *gorm.DB) error {
 
 		event, err := ts.eventService.GetEventByID(req.EventID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrEventNotFound
+		}
 		if err != nil {
-			return errors.New("event not found")
+			return err
 		}
 
-		if event.AvailableQuota < req.Quantity {
-			return errors.New("insufficient tickets available")
+		if err := ts.checkBookingRules(tx, event, req); err != nil {
+			return err
 		}
 
-		totalIDR := event.PriceIDR * float64(req.Quantity)
+		if req.WaitlistEntryID == nil && event.AvailableQuota < req.Quantity {
+			return ErrInsufficientTickets
+		}
 
-		rate, err := ts.rateService.GetCurrentRate()
+		token, err := ts.chains.Resolve(req.ChainID, req.Token)
 		if err != nil {
-			return errors.New("failed to get exchange rate")
+			return err
 		}
 
-		baseUSDTAmount := totalIDR / rate.IDRToUSDTRate
-		feeAmount := baseUSDTAmount * (ts.platformFee / 100)
-		finalUSDTAmount := baseUSDTAmount + feeAmount
+		totalIDR := event.PriceIDR * money.IDR(req.Quantity)
 
-		finalUSDTAmount = math.Round(finalUSDTAmount*1000000) / 1000000
+		var price *bookingPrice
+		var discountIDR money.IDR
+		if req.QuoteID != nil {
+			quote, err := ts.quoteService.claimQuote(tx, *req.QuoteID, req, token)
+			if err != nil {
+				return err
+			}
+			totalIDR = quote.TotalIDR
+			discountIDR = quote.DiscountIDR
+			price = &bookingPrice{
+				Fee: FeeBreakdown{
+					BaseIDR:       quote.BaseIDR,
+					FeeIDR:        quote.FeeIDR,
+					NetworkFeeIDR: quote.NetworkFeeIDR,
+					RuleID:        quote.FeeRuleID,
+					Rule:          quote.FeeRule,
+				},
+				USDTRate:      quote.USDTRate,
+				USDTAmount:    quote.USDTAmount,
+				TokenRate:     quote.TokenRate,
+				TokenAmount:   quote.TokenAmount,
+				TokenDecimals: quote.TokenDecimals,
+			}
+		}
 
-		if err := ts.eventService.UpdateEventQuota(req.EventID, req.Quantity); err != nil {
+		// The promo code is reserved even when a quote already locked the
+		// discount, so usage limits are enforced at booking time.
+		var promo *models.PromoCode
+		if req.PromoCode != "" {
+			var discount money.IDR
+			promo, discount, err = ts.promoService.reserve(tx, req.PromoCode, event, req.CustomerID, totalIDR)
+			if err != nil {
+				return err
+			}
+			if price == nil {
+				discountIDR = discount
+			}
+		}
+
+		if price == nil {
+			rate, err := ts.rateService.GetTokenRate(token)
+			if err != nil {
+				return ErrRateUnavailable
+			}
+			fee, err := ts.feeService.Calculate(event, token.ChainID, totalIDR-discountIDR)
+			if err != nil {
+				return err
+			}
+			price = priceBooking(rate, token, fee)
+		}
+
+		if req.WaitlistEntryID != nil {
+			if err := ts.waitlistService.claimOffer(tx, *req.WaitlistEntryID, req); err != nil {
+				return err
+			}
+		} else if err := reserveEventQuota(tx, req.EventID, req.Quantity); err != nil {
 			return err
 		}
 
-		paymentAddr, err := ts.getAvailablePaymentAddress(tx)
-		if err != nil {
-			return errors.New("no payment address available")
+		paymentMode := PaymentModeAddress
+		var paymentAddr string
+		var amountSuffix money.Units
+		if ts.uniqueAmounts != nil {
+			chain, err := ts.chains.Chain(token.ChainID)
+			if err != nil {
+				return err
+			}
+			price.TokenAmount, amountSuffix, err = ts.uniqueAmounts.Assign(tx, chain, token, price.TokenAmount)
+			if err != nil {
+				return err
+			}
+			paymentMode = PaymentModeUniqueAmount
+			paymentAddr = chain.DepositAddress
+		} else {
+			paymentAddr, err = ts.getAvailablePaymentAddress(tx)
+			if err != nil {
+				return ErrNoPaymentAddress
+			}
 		}
 
+		lockedAt := time.Now()
+		deadline := lockedAt.Add(ts.paymentWindow)
 		transaction := &models.Transaction{
 			CustomerID:      req.CustomerID,
 			EventID:         req.EventID,
 			Quantity:        req.Quantity,
 			TotalIDR:        totalIDR,
-			USDTRate:        rate.IDRToUSDTRate,
-			USDTAmount:      finalUSDTAmount,
+			DiscountIDR:     discountIDR,
+			BaseIDR:         price.Fee.BaseIDR,
+			FeeIDR:          price.Fee.FeeIDR,
+			NetworkFeeIDR:   price.Fee.NetworkFeeIDR,
+			ChargedIDR:      price.Fee.ChargedIDR(),
+			FeeRuleID:       price.Fee.RuleID,
+			FeeRule:         price.Fee.Rule,
+			USDTRate:        price.USDTRate,
+			USDTAmount:      price.USDTAmount,
+			ChainID:         token.ChainID,
+			PaymentToken:    token.Symbol,
+			TokenRate:       price.TokenRate,
+			TokenAmount:     price.TokenAmount,
+			TokenDecimals:   price.TokenDecimals,
+			PaymentMode:     paymentMode,
+			AmountSuffix:    amountSuffix,
 			PaymentAddress:  paymentAddr,
+			QuoteID:         req.QuoteID,
 			Status:          "pending",
-			PaymentLockedAt: func() *time.Time { t := time.Now(); return &t }(),
+			PaymentLockedAt: &lockedAt,
+			PaymentDeadline: &deadline,
+		}
+
+		if promo != nil {
+			transaction.PromoCodeID = &promo.ID
+			transaction.PromoCode = promo.Code
 		}
 
 		if err := tx.Create(transaction).Error; err != nil {
 			return err
 		}
 
-		if err := ts.generateTickets(tx, transaction); err != nil {
+		if promo != nil {
+			if err := ts.promoService.recordRedemption(tx, promo, transaction); err != nil {
+				return err
+			}
+		}
+
+		if req.QuoteID != nil {
+			if err := ts.quoteService.attachTransaction(tx, *req.QuoteID, transaction.ID); err != nil {
+				return err
+			}
+		}
+
+		if req.WaitlistEntryID != nil {
+			if err := ts.waitlistService.attachTransaction(tx, *req.WaitlistEntryID, transaction.ID); err != nil {
+				return err
+			}
+		}
+
+		var seats []models.Seat
+		if event.HasSeating {
+			seats, err = ts.seatService.HoldSeats(tx, event.ID, transaction.ID, req.SeatIDs, deadline)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := ts.generateTickets(tx, transaction, seats); err != nil {
+			return err
+		}
+
+		if err := recordTransactionEvent(tx, TransactionCreated, transaction.ID); err != nil {
 			return err
 		}
 
-		if err := ts.blockchainService.MonitorPayment(transaction.ID, transaction.USDTAmount, transaction.PaymentAddress); err != nil {
+		if err := ts.blockchainService.MonitorPayment(transaction); err != nil {
 			log.Printf("Warning: Failed to start payment monitoring: %v", err)
 		}
 
@@ -113,6 +272,44 @@ func (ts *TransactionService) CreateTransaction(req *CreateTransactionRequest) (
 	return result, nil
 }
 
+func (ts *TransactionService) checkBookingRules(tx *gorm.DB, event *models.Event, req *CreateTransactionRequest) error {
+	if err := ts.eventService.CheckSalesWindow(event, time.Now()); err != nil {
+		return err
+	}
+
+	if event.MaxTicketsPerTransaction > 0 && req.Quantity > event.MaxTicketsPerTransaction {
+		return ErrMaxPerTransaction
+	}
+
+	if err := checkSeatSelection(event, req); err != nil {
+		return err
+	}
+
+	if event.MaxTicketsPerCustomer > 0 {
+		// Serialise bookings by the same customer for the same event until
+		// this transaction commits, so concurrent requests cannot each pass
+		// the limit on a sum that excludes the other.
+		lockKey := fmt.Sprintf("customer_booking:%s:%s", req.CustomerID, req.EventID)
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", lockKey).Error; err != nil {
+			return err
+		}
+
+		var held int64
+		if err := tx.Model(&models.Transaction{}).
+			Where("customer_id = ? AND event_id = ? AND status IN ?", req.CustomerID, req.EventID, []string{"pending", "paid"}).
+			Select("COALESCE(SUM(quantity), 0)").
+			Scan(&held).Error; err != nil {
+			return err
+		}
+
+		if int(held)+req.Quantity > event.MaxTicketsPerCustomer {
+			return ErrMaxPerCustomer
+		}
+	}
+
+	return nil
+}
+
 func (ts *TransactionService) GetTransactionByID(id uuid.UUID) (*models.Transaction, error) {
 	var transaction models.Transaction
 	if err := ts.db.Preload("Customer").Preload("Event").Preload("Tickets").
@@ -122,6 +319,15 @@ func (ts *TransactionService) GetTransactionByID(id uuid.UUID) (*models.Transact
 	return &transaction, nil
 }
 
+func (ts *TransactionService) GetTicketByCode(code string) (*models.Ticket, error) {
+	var ticket models.Ticket
+	if err := ts.db.Preload("Customer").Preload("Event").
+		First(&ticket, "ticket_code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
 func (ts *TransactionService) UpdateTransactionStatus(id uuid.UUID, status string) error {
 	return ts.db.Model(&models.Transaction{}).
 		Where("id = ?", id).
@@ -147,11 +353,33 @@ func (ts *TransactionService) ConfirmPayment(transactionID uuid.UUID, txHash str
 			return err
 		}
 
+		if err := markSeatsSold(tx, transactionID); err != nil {
+			return err
+		}
+
+		if err := recordTransactionEvent(tx, TransactionPaid, transactionID); err != nil {
+			return err
+		}
+
+		if err := notifyPaymentStatus(tx, PaymentStatusUpdate{
+			Type:          PaymentPaid,
+			TransactionID: transactionID,
+			Status:        "paid",
+			TxHash:        txHash,
+			Amount:        transaction.TokenAmount.Format(transaction.TokenDecimals),
+			Token:         transaction.PaymentToken,
+		}); err != nil {
+			return err
+		}
+
 		blockchainTx := &models.BlockchainTransaction{
 			TransactionID: transactionID,
+			ChainID:       transaction.ChainID,
 			TxHash:        txHash,
 			ToAddress:     transaction.PaymentAddress,
-			Amount:        transaction.USDTAmount,
+			Amount:        transaction.TokenAmount,
+			Token:         transaction.PaymentToken,
+			TokenDecimals: transaction.TokenDecimals,
 			Status:        "confirmed",
 		}
 
@@ -159,6 +387,217 @@ func (ts *TransactionService) ConfirmPayment(transactionID uuid.UUID, txHash str
 	})
 }
 
+func (ts *TransactionService) StartExpiryWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			expired, err := ts.ExpirePendingTransactions()
+			if err != nil {
+				log.Printf("Failed to expire pending transactions: %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("Expired %d pending transactions", expired)
+			}
+		}
+	}()
+}
+
+func (ts *TransactionService) ExpirePendingTransactions() (int, error) {
+	var transactions []models.Transaction
+	if err := ts.db.Where("status = ? AND payment_deadline < ?", "pending", time.Now()).
+		Find(&transactions).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range transactions {
+		ok, err := ts.expireTransaction(&transactions[i])
+		if err != nil {
+			log.Printf("Failed to expire transaction %s: %v", transactions[i].ID, err)
+			continue
+		}
+		if ok {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+func (ts *TransactionService) expireTransaction(transaction *models.Transaction) (bool, error) {
+	updated := false
+
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Transaction{}).
+			Where("id = ? AND status = ?", transaction.ID, "pending").
+			Update("status", "expired")
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		updated = true
+
+		if err := tx.Model(&models.Ticket{}).
+			Where("transaction_id = ?", transaction.ID).
+			Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+
+		if err := releasePromoRedemption(tx, transaction.ID); err != nil {
+			return err
+		}
+
+		if err := releaseSeats(tx, transaction.ID); err != nil {
+			return err
+		}
+
+		if err := restoreEventQuota(tx, transaction.EventID, transaction.Quantity); err != nil {
+			return fmt.Errorf("failed to restore quota: %w", err)
+		}
+
+		if err := recordTransactionEvent(tx, TransactionExpired, transaction.ID); err != nil {
+			return err
+		}
+
+		return notifyPaymentStatus(tx, PaymentStatusUpdate{
+			Type:          PaymentExpired,
+			TransactionID: transaction.ID,
+			Status:        "expired",
+		})
+	})
+	if err != nil || !updated {
+		return false, err
+	}
+
+	ts.eventService.notifyQuotaRestored(transaction.EventID)
+	return true, nil
+}
+
+func (ts *TransactionService) StartReminderWorker(interval time.Duration) {
+	if len(ts.reminders) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			sent, err := ts.SendPaymentReminders()
+			if err != nil {
+				log.Printf("Failed to send payment reminders: %v", err)
+				continue
+			}
+			if sent > 0 {
+				log.Printf("Sent %d payment reminders", sent)
+			}
+		}
+	}()
+}
+
+// SendPaymentReminders reminds customers of pending transactions whose
+// deadline is getting close. A reminder is due once the deadline is less than
+// one of the configured offsets away; a transaction that passed several
+// offsets since its last reminder gets a single one.
+func (ts *TransactionService) SendPaymentReminders() (int, error) {
+	if len(ts.reminders) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	var transactions []models.Transaction
+	if err := ts.db.Where("status = ? AND payment_deadline > ? AND payment_deadline <= ?", "pending", now, now.Add(ts.reminders[0])).
+		Find(&transactions).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range transactions {
+		due, ok := ts.dueReminder(&transactions[i], now)
+		if !ok {
+			continue
+		}
+		ok, err := ts.sendPaymentReminder(&transactions[i], due, now)
+		if err != nil {
+			log.Printf("Failed to remind transaction %s: %v", transactions[i].ID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// dueReminder returns when the latest reminder offset the transaction has
+// passed was reached, if it has not been reminded since. Offsets as long as
+// the whole payment window are skipped; the order has just been placed.
+func (ts *TransactionService) dueReminder(transaction *models.Transaction, now time.Time) (time.Time, bool) {
+	if transaction.PaymentDeadline == nil || transaction.PaymentLockedAt == nil {
+		return time.Time{}, false
+	}
+
+	for i := len(ts.reminders) - 1; i >= 0; i-- {
+		due := transaction.PaymentDeadline.Add(-ts.reminders[i])
+		if due.After(now) {
+			continue
+		}
+		if !due.After(*transaction.PaymentLockedAt) {
+			return time.Time{}, false
+		}
+		if transaction.PaymentRemindedAt != nil && !transaction.PaymentRemindedAt.Before(due) {
+			return time.Time{}, false
+		}
+		return due, true
+	}
+	return time.Time{}, false
+}
+
+// sendPaymentReminder records the reminder as an event for the outbox sinks
+// and the payment status stream. Marking the transaction reminded in the same
+// database transaction keeps replicas from sending it twice.
+func (ts *TransactionService) sendPaymentReminder(transaction *models.Transaction, due, now time.Time) (bool, error) {
+	sent := false
+
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Transaction{}).
+			Where("id = ? AND status = ? AND (payment_reminded_at IS NULL OR payment_reminded_at < ?)", transaction.ID, "pending", due).
+			Update("payment_reminded_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		sent = true
+
+		deadline := *transaction.PaymentDeadline
+		minutesLeft := max(int(deadline.Sub(now).Round(time.Minute)/time.Minute), 1)
+		if err := recordTransactionEventData(tx, TransactionPaymentReminder, transaction.ID, map[string]interface{}{
+			"reminder": map[string]interface{}{
+				"payment_deadline": deadline,
+				"minutes_left":     minutesLeft,
+			},
+		}); err != nil {
+			return err
+		}
+
+		return notifyPaymentStatus(tx, PaymentStatusUpdate{
+			Type:            PaymentReminder,
+			TransactionID:   transaction.ID,
+			Status:          "pending",
+			PaymentDeadline: &deadline,
+			MinutesLeft:     minutesLeft,
+		})
+	})
+	return sent, err
+}
+
 func (ts *TransactionService) getAvailablePaymentAddress(tx *gorm.DB) (string, error) {
 	var paymentAddr models.PaymentAddress
 	if err := tx.Where("is_used = ?", false).First(&paymentAddr).Error; err != nil {
@@ -186,7 +625,7 @@ func (ts *TransactionService) getAvailablePaymentAddress(tx *gorm.DB) (string, e
 	return paymentAddr.Address, nil
 }
 
-func (ts *TransactionService) generateTickets(tx *gorm.DB, transaction *models.Transaction) error {
+func (ts *TransactionService) generateTickets(tx *gorm.DB, transaction *models.Transaction, seats []models.Seat) error {
 	for i := 0; i < transaction.Quantity; i++ {
 		ticket := &models.Ticket{
 			TransactionID: transaction.ID,
@@ -196,6 +635,11 @@ func (ts *TransactionService) generateTickets(tx *gorm.DB, transaction *models.T
 			Status:        "active",
 		}
 
+		if i < len(seats) {
+			ticket.SeatID = &seats[i].ID
+			ticket.Seat = seatLabel(&seats[i])
+		}
+
 		if err := tx.Create(ticket).Error; err != nil {
 			return err
 		}
@@ -206,3 +650,26 @@ func (ts *TransactionService) generateTickets(tx *gorm.DB, transaction *models.T
 func (ts *TransactionService) generateTicketCode(transactionID uuid.UUID, index int) string {
 	return fmt.Sprintf("TIX-%s-%d", transactionID.String()[:8], index+1)
 }
+
+func checkSeatSelection(event *models.Event, req *CreateTransactionRequest) error {
+	if !event.HasSeating {
+		if len(req.SeatIDs) > 0 {
+			return ErrSeatingNotSupported
+		}
+		return nil
+	}
+
+	if len(req.SeatIDs) != req.Quantity {
+		return ErrSeatSelection
+	}
+
+	seen := make(map[uuid.UUID]bool, len(req.SeatIDs))
+	for _, id := range req.SeatIDs {
+		if seen[id] {
+			return ErrSeatSelection
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
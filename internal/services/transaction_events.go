@@ -0,0 +1,114 @@
+package services
+
+import (
+	"encoding/json"
+	"sermorpheus-engine-test/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Transaction lifecycle events, named as they appear in published payloads.
+const (
+	TransactionCreated   = "transaction.created"
+	TransactionPaid      = "transaction.paid"
+	TransactionExpired   = "transaction.expired"
+	TransactionCancelled = "transaction.cancelled"
+
+	TransactionRefundPending = "transaction.refund_pending"
+	TransactionRefunded      = "transaction.refunded"
+
+	TransactionPaymentReminder = "transaction.payment_reminder"
+)
+
+var TransactionEventTypes = []string{
+	TransactionCreated,
+	TransactionPaid,
+	TransactionExpired,
+	TransactionCancelled,
+	TransactionRefundPending,
+	TransactionRefunded,
+	TransactionPaymentReminder,
+}
+
+// WaitlistOffered is published when tickets freed up for an event are held
+// for a waitlisted customer.
+const WaitlistOffered = "waitlist.offered"
+
+var WaitlistEventTypes = []string{WaitlistOffered}
+
+// EventEnvelope is the JSON body every sink publishes. ID stays the same
+// across retries so consumers can drop duplicates.
+type EventEnvelope struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// recordTransactionEvent writes a lifecycle event to the outbox. It must run
+// inside the database transaction that made the change: the payload is a
+// snapshot read within that transaction, and the event exists if and only
+// if the change commits.
+func recordTransactionEvent(tx *gorm.DB, eventType string, transactionID uuid.UUID) error {
+	return recordTransactionEventData(tx, eventType, transactionID, nil)
+}
+
+// recordTransactionEventData is recordTransactionEvent with extra fields for
+// the event's data.
+func recordTransactionEventData(tx *gorm.DB, eventType string, transactionID uuid.UUID, extra map[string]interface{}) error {
+	var transaction models.Transaction
+	if err := tx.Preload("Tickets").First(&transaction, "id = ?", transactionID).Error; err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{"transaction": &transaction}
+	for key, value := range extra {
+		data[key] = value
+	}
+	if eventType == TransactionRefundPending || eventType == TransactionRefunded {
+		var refund models.Refund
+		if err := tx.Where("transaction_id = ?", transactionID).
+			Order("created_at DESC").
+			First(&refund).Error; err == nil {
+			data["refund"] = &refund
+		}
+	}
+
+	return recordOutboxEvent(tx, "transaction", transactionID, eventType, data)
+}
+
+// recordWaitlistEvent writes a waitlist event to the outbox, under the same
+// rules as recordTransactionEvent.
+func recordWaitlistEvent(tx *gorm.DB, eventType string, entryID uuid.UUID) error {
+	var entry models.WaitlistEntry
+	if err := tx.First(&entry, "id = ?", entryID).Error; err != nil {
+		return err
+	}
+	return recordOutboxEvent(tx, "waitlist_entry", entryID, eventType, map[string]interface{}{"waitlist_entry": &entry})
+}
+
+func recordOutboxEvent(tx *gorm.DB, aggregateType string, aggregateID uuid.UUID, eventType string, data map[string]interface{}) error {
+	envelope := EventEnvelope{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.OutboxEvent{
+		ID:            envelope.ID,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       string(payload),
+		Status:        "pending",
+		NextAttemptAt: envelope.CreatedAt,
+		CreatedAt:     envelope.CreatedAt,
+	}).Error
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sermorpheus-engine-test/internal/models"
+	"sermorpheus-engine-test/internal/money"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+const (
+	// PaymentModeAddress gives every order its own payment address.
+	PaymentModeAddress = "address"
+	// PaymentModeUniqueAmount has every order pay to the chain's shared
+	// deposit address and tells orders apart by amount.
+	PaymentModeUniqueAmount = "unique_amount"
+)
+
+// UniqueAmountAllocator gives each pending order on a shared deposit address
+// an amount no other pending order for the same token expects, by adding a
+// small random suffix to the priced amount. Amounts of orders that expired
+// or were cancelled within the reuse grace period stay taken too, so a late
+// payment for such an order cannot be matched to a new one.
+type UniqueAmountAllocator struct {
+	places     int
+	maxSuffix  int64
+	reuseGrace time.Duration
+}
+
+// NewUniqueAmountAllocator places suffixes at the given decimal place of
+// stablecoin amounts (4 means steps of 0.0001) and allows up to maxSuffix
+// steps. Native coins use their last payable decimal place instead so the
+// suffix stays negligible in value. Amounts are reused reuseGrace after their
+// order expired or was cancelled.
+func NewUniqueAmountAllocator(chains *ChainRegistry, places, maxSuffix int, reuseGrace time.Duration) (*UniqueAmountAllocator, error) {
+	if places < 0 || maxSuffix < 1 {
+		return nil, fmt.Errorf("unique amount decimals must be non-negative and the max suffix at least 1")
+	}
+	if reuseGrace < 0 {
+		return nil, fmt.Errorf("unique amount reuse grace period cannot be negative")
+	}
+
+	for _, chain := range chains.Chains() {
+		if !common.IsHexAddress(chain.DepositAddress) {
+			return nil, fmt.Errorf("chain %s has no valid deposit address configured", chain.Key)
+		}
+	}
+
+	return &UniqueAmountAllocator{places: places, maxSuffix: int64(maxSuffix), reuseGrace: reuseGrace}, nil
+}
+
+// Assign returns the amount the order must pay and the suffix added to base.
+// It must run inside the transaction that creates the order: an advisory lock
+// on the deposit address and token serialises assignments until that
+// transaction commits.
+func (a *UniqueAmountAllocator) Assign(tx *gorm.DB, chain *Chain, token *PaymentToken, base money.Units) (money.Units, money.Units, error) {
+	lockKey := fmt.Sprintf("unique_amount:%d:%s:%s", chain.ID, chain.DepositAddress, token.Symbol)
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", lockKey).Error; err != nil {
+		return money.Units{}, money.Units{}, err
+	}
+
+	places := a.places
+	if token.IsNative() {
+		places = maxTokenPlaces
+	}
+	places = min(places, token.Decimals)
+
+	step := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(token.Decimals-places)), nil)
+	start := base.RoundUp(token.Decimals, places)
+	highest := money.NewUnits(new(big.Int).Add(start.Int(), new(big.Int).Mul(step, big.NewInt(a.maxSuffix))))
+
+	var taken []money.Units
+	if err := tx.Model(&models.Transaction{}).
+		Where("chain_id = ? AND payment_address = ? AND payment_token = ?",
+			chain.ID, chain.DepositAddress, token.Symbol).
+		Where("status = ? OR (status IN ? AND updated_at > ?)",
+			"pending", []string{"expired", "cancelled"}, time.Now().Add(-a.reuseGrace)).
+		Where("token_amount > ? AND token_amount <= ?", start, highest).
+		Pluck("token_amount", &taken).Error; err != nil {
+		return money.Units{}, money.Units{}, err
+	}
+
+	inUse := make(map[string]bool, len(taken))
+	for _, amount := range taken {
+		inUse[amount.String()] = true
+	}
+
+	amount, ok := freeUniqueAmount(start, step, a.maxSuffix, rand.Int63n(a.maxSuffix), inUse)
+	if !ok {
+		return money.Units{}, money.Units{}, ErrNoUniqueAmount
+	}
+	return amount, money.NewUnits(new(big.Int).Sub(amount.Int(), base.Int())), nil
+}
+
+// freeUniqueAmount returns the first amount start + suffix*step not in inUse,
+// trying suffixes from first+1 up to maxSuffix and then wrapping around to 1.
+func freeUniqueAmount(start money.Units, step *big.Int, maxSuffix, first int64, inUse map[string]bool) (money.Units, bool) {
+	for i := int64(0); i < maxSuffix; i++ {
+		suffix := (first+i)%maxSuffix + 1
+		amount := money.NewUnits(new(big.Int).Add(start.Int(), new(big.Int).Mul(step, big.NewInt(suffix))))
+		if !inUse[amount.String()] {
+			return amount, true
+		}
+	}
+	return money.Units{}, false
+}
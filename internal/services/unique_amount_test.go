@@ -0,0 +1,46 @@
+package services
+
+import (
+	"math/big"
+	"sermorpheus-engine-test/internal/money"
+	"testing"
+)
+
+func TestFreeUniqueAmount(t *testing.T) {
+	start := money.UnitsFromInt64(6170000)
+	step := big.NewInt(100)
+	taken := func(amounts ...int64) map[string]bool {
+		inUse := make(map[string]bool)
+		for _, amount := range amounts {
+			inUse[money.UnitsFromInt64(amount).String()] = true
+		}
+		return inUse
+	}
+
+	tests := []struct {
+		name   string
+		max    int64
+		first  int64
+		inUse  map[string]bool
+		want   int64
+		wantOK bool
+	}{
+		{name: "first suffix", max: 999, first: 0, inUse: taken(), want: 6170100, wantOK: true},
+		{name: "random start", max: 999, first: 41, inUse: taken(), want: 6174200, wantOK: true},
+		{name: "skips taken", max: 999, first: 0, inUse: taken(6170100, 6170200), want: 6170300, wantOK: true},
+		{name: "wraps around", max: 3, first: 2, inUse: taken(6170300), want: 6170100, wantOK: true},
+		{name: "never the base amount", max: 1, first: 0, inUse: taken(6170000), want: 6170100, wantOK: true},
+		{name: "exhausted", max: 3, first: 1, inUse: taken(6170100, 6170200, 6170300), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := freeUniqueAmount(start, step, tt.max, tt.first, tt.inUse)
+		if ok != tt.wantOK {
+			t.Errorf("%s: freeUniqueAmount ok = %v, want %v", tt.name, ok, tt.wantOK)
+			continue
+		}
+		if ok && got.String() != money.UnitsFromInt64(tt.want).String() {
+			t.Errorf("%s: freeUniqueAmount = %s, want %d", tt.name, got, tt.want)
+		}
+	}
+}
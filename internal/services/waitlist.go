@@ -0,0 +1,263 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sermorpheus-engine-test/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WaitlistService struct {
+	db           *gorm.DB
+	eventService *EventService
+	offerTTL     time.Duration
+}
+
+type WaitlistPosition struct {
+	Entry    *models.WaitlistEntry `json:"entry"`
+	Position int64                 `json:"position"`
+}
+
+func NewWaitlistService(db *gorm.DB, eventService *EventService, offerTTL time.Duration) *WaitlistService {
+	ws := &WaitlistService{
+		db:           db,
+		eventService: eventService,
+		offerTTL:     offerTTL,
+	}
+
+	eventService.OnQuotaRestored(func(eventID uuid.UUID) {
+		if err := ws.ProcessEvent(eventID); err != nil {
+			log.Printf("Failed to process waitlist for event %s: %v", eventID, err)
+		}
+	})
+
+	return ws
+}
+
+func (ws *WaitlistService) Join(eventID, customerID uuid.UUID, quantity int) (*models.WaitlistEntry, error) {
+	event, err := ws.eventService.GetEventByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ws.eventService.CheckSalesWindow(event, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if event.HasSeating {
+		return nil, invalid("waitlist is not available for seated events")
+	}
+
+	if event.MaxTicketsPerTransaction > 0 && quantity > event.MaxTicketsPerTransaction {
+		return nil, ErrMaxPerTransaction
+	}
+
+	if event.AvailableQuota >= quantity {
+		return nil, invalid("tickets are still available for this event")
+	}
+
+	entry := &models.WaitlistEntry{
+		EventID:    eventID,
+		CustomerID: customerID,
+		Quantity:   quantity,
+		Status:     "waiting",
+	}
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		// Joins by one customer for one event take turns, so two concurrent
+		// requests cannot both find no active entry and both add one.
+		lockKey := fmt.Sprintf("waitlist_join:%s:%s", customerID, eventID)
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", lockKey).Error; err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Model(&models.WaitlistEntry{}).
+			Where("event_id = ? AND customer_id = ? AND status IN ?", eventID, customerID, []string{"waiting", "offered"}).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return invalid("customer is already on the waitlist for this event")
+		}
+
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (ws *WaitlistService) GetEntry(id uuid.UUID) (*WaitlistPosition, error) {
+	var entry models.WaitlistEntry
+	if err := ws.db.Preload("Customer").Preload("Event").First(&entry, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	result := &WaitlistPosition{Entry: &entry}
+	if entry.Status == "waiting" {
+		if err := ws.db.Model(&models.WaitlistEntry{}).
+			Where("event_id = ? AND status = ? AND created_at <= ?", entry.EventID, "waiting", entry.CreatedAt).
+			Count(&result.Position).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (ws *WaitlistService) Leave(id uuid.UUID) error {
+	var entry models.WaitlistEntry
+	released := false
+
+	err := ws.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&entry, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		if entry.Status != "waiting" && entry.Status != "offered" {
+			return invalid("waitlist entry is no longer active")
+		}
+
+		released = entry.Status == "offered"
+		if err := tx.Model(&entry).Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+
+		// An open offer holds tickets; they go back to the event.
+		if released {
+			return restoreEventQuota(tx, entry.EventID, entry.Quantity)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if released {
+		ws.eventService.notifyQuotaRestored(entry.EventID)
+	}
+	return nil
+}
+
+func (ws *WaitlistService) ProcessEvent(eventID uuid.UUID) error {
+	return ws.db.Transaction(func(tx *gorm.DB) error {
+		var event models.Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&event, "id = ?", eventID).Error; err != nil {
+			return err
+		}
+
+		if event.Status == "cancelled" {
+			return nil
+		}
+
+		var entries []models.WaitlistEntry
+		if err := tx.Where("event_id = ? AND status = ?", eventID, "waiting").
+			Order("created_at ASC").
+			Find(&entries).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		expiresAt := now.Add(ws.offerTTL)
+		for _, entry := range entries {
+			result := tx.Model(&models.Event{}).
+				Where("id = ? AND available_quota >= ?", eventID, entry.Quantity).
+				Update("available_quota", gorm.Expr("available_quota - ?", entry.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				break
+			}
+
+			if err := tx.Model(&models.WaitlistEntry{}).
+				Where("id = ?", entry.ID).
+				Updates(map[string]interface{}{
+					"status":           "offered",
+					"offered_at":       now,
+					"offer_expires_at": expiresAt,
+				}).Error; err != nil {
+				return err
+			}
+
+			if err := recordWaitlistEvent(tx, WaitlistOffered, entry.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (ws *WaitlistService) StartOfferExpiryWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := ws.ExpireOffers(); err != nil {
+				log.Printf("Failed to expire waitlist offers: %v", err)
+			}
+		}
+	}()
+}
+
+func (ws *WaitlistService) ExpireOffers() error {
+	var entries []models.WaitlistEntry
+	if err := ws.db.Where("status = ? AND offer_expires_at < ?", "offered", time.Now()).
+		Find(&entries).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		expired := false
+		err := ws.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.WaitlistEntry{}).
+				Where("id = ? AND status = ?", entry.ID, "offered").
+				Update("status", "expired")
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			expired = true
+			return restoreEventQuota(tx, entry.EventID, entry.Quantity)
+		})
+		if err != nil {
+			log.Printf("Failed to expire waitlist offer %s: %v", entry.ID, err)
+			continue
+		}
+
+		if expired {
+			ws.eventService.notifyQuotaRestored(entry.EventID)
+		}
+	}
+
+	return nil
+}
+
+func (ws *WaitlistService) claimOffer(tx *gorm.DB, entryID uuid.UUID, req *CreateTransactionRequest) error {
+	result := tx.Model(&models.WaitlistEntry{}).
+		Where("id = ? AND customer_id = ? AND event_id = ? AND quantity = ? AND status = ? AND offer_expires_at > ?",
+			entryID, req.CustomerID, req.EventID, req.Quantity, "offered", time.Now()).
+		Update("status", "converted")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWaitlistOffer
+	}
+	return nil
+}
+
+func (ws *WaitlistService) attachTransaction(tx *gorm.DB, entryID, transactionID uuid.UUID) error {
+	return tx.Model(&models.WaitlistEntry{}).
+		Where("id = ?", entryID).
+		Update("transaction_id", transactionID).Error
+}
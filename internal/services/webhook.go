@@ -0,0 +1,452 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sermorpheus-engine-test/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	webhookBatchSize      = 50
+	webhookMaxBackoff     = 6 * time.Hour
+	webhookMaxResponseLog = 2048
+	webhookAllEvents      = "*"
+)
+
+// WebhookService delivers transaction lifecycle events to subscribed URLs.
+// Deliveries are queued in the database by the outbox relay and sent by a
+// background worker, which retries failures with exponential backoff. Every request is signed
+// with the subscription's secret:
+//
+//	X-Webhook-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+type WebhookService struct {
+	db          *gorm.DB
+	client      *http.Client
+	timeout     time.Duration
+	maxAttempts int
+	retryBase   time.Duration
+}
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Events      []string `json:"events,omitempty"`
+	Status      *string  `json:"status,omitempty"`
+}
+
+// CreatedWebhook is returned once on creation; the secret cannot be read
+// back afterwards.
+type CreatedWebhook struct {
+	*models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+func NewWebhookService(db *gorm.DB, timeout time.Duration, maxAttempts int, retryBase time.Duration) *WebhookService {
+	return &WebhookService{
+		db:          db,
+		client:      &http.Client{Timeout: timeout},
+		timeout:     timeout,
+		maxAttempts: max(maxAttempts, 1),
+		retryBase:   retryBase,
+	}
+}
+
+// Enqueue queues one delivery of an outbox event per matching active
+// subscription. It does nothing if the event was queued before, so the
+// outbox relay can safely retry it.
+func (ws *WebhookService) Enqueue(event *models.OutboxEvent) error {
+	var queued int64
+	if err := ws.db.Model(&models.WebhookDelivery{}).
+		Where("event_id = ? AND redelivery_of IS NULL", event.ID).
+		Count(&queued).Error; err != nil {
+		return err
+	}
+	if queued > 0 {
+		return nil
+	}
+
+	var subscriptions []models.WebhookSubscription
+	if err := ws.db.Where("status = ?", "active").Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !subscribesTo(&subscription, event.EventType) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.EventType,
+			Payload:        event.Payload,
+			Status:         "pending",
+			NextAttemptAt:  now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return ws.db.Create(&deliveries).Error
+}
+
+func subscribesTo(subscription *models.WebhookSubscription, eventType string) bool {
+	for _, event := range strings.Split(subscription.Events, ",") {
+		if event == webhookAllEvents || event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (ws *WebhookService) StartDeliveryWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := ws.DeliverDue(); err != nil {
+				log.Printf("Failed to deliver webhooks: %v", err)
+			}
+		}
+	}()
+}
+
+// DeliverDue sends the deliveries whose next attempt is due. Claimed
+// deliveries are leased for twice the request timeout so other replicas skip
+// them while they are in flight.
+func (ws *WebhookService) DeliverDue() (int, error) {
+	var deliveries []models.WebhookDelivery
+
+	err := ws.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", "pending", now).
+			Order("next_attempt_at").
+			Limit(webhookBatchSize).
+			Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(2*ws.timeout)).Error
+	})
+	if err != nil || len(deliveries) == 0 {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			if err := ws.deliver(delivery); err != nil {
+				log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+			}
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+func (ws *WebhookService) deliver(delivery *models.WebhookDelivery) error {
+	var subscription models.WebhookSubscription
+	if err := ws.db.First(&subscription, "id = ?", delivery.SubscriptionID).Error; err != nil {
+		return err
+	}
+	if subscription.Status != "active" {
+		return ws.db.Model(delivery).Updates(map[string]interface{}{
+			"status":     "cancelled",
+			"last_error": "subscription disabled",
+		}).Error
+	}
+
+	attempt := models.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts + 1,
+	}
+
+	start := time.Now()
+	statusCode, body, err := ws.send(&subscription, delivery)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	attempt.StatusCode = statusCode
+	attempt.ResponseBody = body
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	updates := map[string]interface{}{
+		"attempts":         attempt.Attempt,
+		"last_status_code": statusCode,
+		"last_error":       attempt.Error,
+	}
+	now := time.Now()
+	switch {
+	case err == nil:
+		updates["status"] = "delivered"
+		updates["delivered_at"] = now
+	case attempt.Attempt >= ws.maxAttempts:
+		updates["status"] = "failed"
+		log.Printf("Webhook delivery %s to %s failed after %d attempts: %v", delivery.ID, subscription.URL, attempt.Attempt, err)
+	default:
+		updates["next_attempt_at"] = now.Add(ws.backoff(attempt.Attempt))
+	}
+
+	return ws.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id = ?", delivery.ID).
+			Updates(updates).Error
+	})
+}
+
+// send posts the payload and treats any 2xx response as delivered.
+func (ws *WebhookService) send(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, string, error) {
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sermorpheus-webhooks/1.0")
+	req.Header.Set("X-Webhook-ID", delivery.EventID.String())
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", SignWebhookPayload(subscription.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponseLog))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// backoff doubles the wait after every failed attempt, starting from the
+// configured base.
+func (ws *WebhookService) backoff(attempt int) time.Duration {
+	wait := ws.retryBase
+	for i := 1; i < attempt && wait < webhookMaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, webhookMaxBackoff)
+}
+
+// SignWebhookPayload returns the X-Webhook-Signature value for a payload.
+// Receivers should recompute it and reject requests with an old timestamp.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (ws *WebhookService) ListSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := ws.db.Order("created_at DESC").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (ws *WebhookService) CreateSubscription(req *CreateWebhookRequest) (*CreatedWebhook, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      events,
+		Secret:      "whsec_" + hex.EncodeToString(secret),
+		Status:      "active",
+	}
+	if err := ws.db.Create(subscription).Error; err != nil {
+		return nil, err
+	}
+
+	return &CreatedWebhook{WebhookSubscription: subscription, Secret: subscription.Secret}, nil
+}
+
+func (ws *WebhookService) UpdateSubscription(id uuid.UUID, req *UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := ws.db.First(&subscription, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+		subscription.URL = *req.URL
+	}
+	if req.Description != nil {
+		subscription.Description = *req.Description
+	}
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		subscription.Events = events
+	}
+	if req.Status != nil {
+		if *req.Status != "active" && *req.Status != "disabled" {
+			return nil, errors.New("status must be active or disabled")
+		}
+		subscription.Status = *req.Status
+	}
+
+	if err := ws.db.Save(&subscription).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (ws *WebhookService) DisableSubscription(id uuid.UUID) error {
+	result := ws.db.Model(&models.WebhookSubscription{}).
+		Where("id = ?", id).
+		Update("status", "disabled")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (ws *WebhookService) ListDeliveries(subscriptionID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	query := ws.db.Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (ws *WebhookService) GetDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := ws.db.Preload("AttemptLog", func(db *gorm.DB) *gorm.DB {
+		return db.Order("attempt")
+	}).First(&delivery, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Redeliver queues a fresh copy of a delivery, with the same event ID and
+// payload, for immediate sending.
+func (ws *WebhookService) Redeliver(id uuid.UUID) (*models.WebhookDelivery, error) {
+	var original models.WebhookDelivery
+	if err := ws.db.First(&original, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         "pending",
+		NextAttemptAt:  time.Now(),
+		RedeliveryOf:   &original.ID,
+	}
+	if err := ws.db.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func validateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// normalizeWebhookEvents validates the requested event types; an empty list
+// subscribes to every event.
+func normalizeWebhookEvents(events []string) (string, error) {
+	if len(events) == 0 {
+		return webhookAllEvents, nil
+	}
+
+	known := make(map[string]bool, len(TransactionEventTypes)+len(WaitlistEventTypes))
+	for _, eventType := range TransactionEventTypes {
+		known[eventType] = true
+	}
+	for _, eventType := range WaitlistEventTypes {
+		known[eventType] = true
+	}
+
+	var normalized []string
+	for _, event := range events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if event == webhookAllEvents {
+			return webhookAllEvents, nil
+		}
+		if !known[event] {
+			return "", fmt.Errorf("unknown event type %q", event)
+		}
+		normalized = append(normalized, event)
+	}
+	return strings.Join(normalized, ","), nil
+}
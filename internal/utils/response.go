@@ -11,6 +11,7 @@ type APIResponse struct {
 	Message   string      `json:"message"`
 	Data      interface{} `json:"data,omitempty"`
 	Error     string      `json:"error,omitempty"`
+	Code      string      `json:"code,omitempty"`
 	Timestamp time.Time   `json:"timestamp"`
 }
 
@@ -33,3 +34,14 @@ func ErrorResponse(c *gin.Context, statusCode int, message string, errorDetail s
 	}
 	c.JSON(statusCode, response)
 }
+
+func ErrorResponseWithCode(c *gin.Context, statusCode int, message string, code string, errorDetail string) {
+	response := APIResponse{
+		Success:   false,
+		Message:   message,
+		Error:     errorDetail,
+		Code:      code,
+		Timestamp: time.Now(),
+	}
+	c.JSON(statusCode, response)
+}
@@ -4,6 +4,9 @@
 -- Enable UUID extension
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
+-- Enable trigram extension for location search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 -- Create database schema (if running as separate script)
 -- Note: When using GORM auto-migration, these tables will be created automatically
 -- This script is for manual setup or reference
@@ -14,10 +17,19 @@ CREATE TABLE IF NOT EXISTS events (
     name VARCHAR(255) NOT NULL,
     description TEXT,
     location VARCHAR(255) NOT NULL,
+    organizer VARCHAR(255),
     schedule TIMESTAMP WITH TIME ZONE NOT NULL,
-    price_idr DECIMAL(15,2) NOT NULL,
+    price_idr BIGINT NOT NULL,
     quota INTEGER NOT NULL,
     available_quota INTEGER NOT NULL,
+    sale_start_at TIMESTAMP WITH TIME ZONE,
+    sale_end_at TIMESTAMP WITH TIME ZONE,
+    max_tickets_per_transaction INTEGER DEFAULT 0,
+    max_tickets_per_customer INTEGER DEFAULT 0,
+    has_seating BOOLEAN DEFAULT FALSE,
+    status VARCHAR(20) DEFAULT 'active',
+    cancel_reason TEXT,
+    cancelled_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     deleted_at TIMESTAMP WITH TIME ZONE
@@ -29,6 +41,7 @@ CREATE TABLE IF NOT EXISTS customers (
     email VARCHAR(255) UNIQUE NOT NULL,
     name VARCHAR(255) NOT NULL,
     phone VARCHAR(50),
+    locale VARCHAR(10),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
@@ -39,12 +52,31 @@ CREATE TABLE IF NOT EXISTS transactions (
     customer_id UUID NOT NULL REFERENCES customers(id),
     event_id UUID NOT NULL REFERENCES events(id),
     quantity INTEGER NOT NULL,
-    total_idr DECIMAL(15,2) NOT NULL,
-    usdt_rate DECIMAL(15,6) NOT NULL,
-    usdt_amount DECIMAL(15,6) NOT NULL,
+    total_idr BIGINT NOT NULL,
+    promo_code_id UUID,
+    promo_code VARCHAR(64),
+    discount_idr BIGINT DEFAULT 0,
+    base_idr BIGINT DEFAULT 0,
+    fee_idr BIGINT DEFAULT 0,
+    network_fee_idr BIGINT DEFAULT 0,
+    charged_idr BIGINT DEFAULT 0,
+    fee_rule_id UUID,
+    fee_rule TEXT,
+    usdt_rate NUMERIC(20,8) NOT NULL,
+    usdt_amount NUMERIC(78,0) NOT NULL,
+    chain_id BIGINT DEFAULT 97,
+    payment_token VARCHAR(10) DEFAULT 'USDT',
+    token_rate NUMERIC(20,8),
+    token_amount NUMERIC(78,0),
+    token_decimals INTEGER DEFAULT 6,
+    payment_mode VARCHAR(20) DEFAULT 'address',
+    amount_suffix NUMERIC(78,0),
     payment_address VARCHAR(42),
+    quote_id UUID,
     status VARCHAR(20) DEFAULT 'pending',
     payment_locked_at TIMESTAMP WITH TIME ZONE,
+    payment_deadline TIMESTAMP WITH TIME ZONE,
+    payment_reminded_at TIMESTAMP WITH TIME ZONE,
     payment_confirmed_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
@@ -57,6 +89,8 @@ CREATE TABLE IF NOT EXISTS tickets (
     event_id UUID NOT NULL REFERENCES events(id),
     customer_id UUID NOT NULL REFERENCES customers(id),
     ticket_code VARCHAR(100) UNIQUE NOT NULL,
+    seat_id UUID,
+    seat VARCHAR(100),
     status VARCHAR(20) DEFAULT 'active',
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
@@ -76,6 +110,12 @@ CREATE TABLE IF NOT EXISTS payment_addresses (
 CREATE TABLE IF NOT EXISTS usdt_rates (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     idr_to_usdt_rate DECIMAL(15,6) NOT NULL,
+    market_rate DECIMAL(15,6),
+    usdt_usd_rate DECIMAL(15,6),
+    usd_idr_rate DECIMAL(15,6),
+    spread_percent DECIMAL(5,2),
+    source VARCHAR(50),
+    providers TEXT,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
@@ -83,27 +123,356 @@ CREATE TABLE IF NOT EXISTS usdt_rates (
 CREATE TABLE IF NOT EXISTS blockchain_transactions (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     transaction_id UUID NOT NULL REFERENCES transactions(id),
+    chain_id BIGINT DEFAULT 97,
     tx_hash VARCHAR(66) UNIQUE,
     from_address VARCHAR(42),
     to_address VARCHAR(42),
-    amount DECIMAL(15,6),
+    amount NUMERIC(78,0),
+    token VARCHAR(10) DEFAULT 'USDT',
+    token_decimals INTEGER DEFAULT 6,
     confirmations INTEGER DEFAULT 0,
     status VARCHAR(20) DEFAULT 'pending',
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- Refunds table
+CREATE TABLE IF NOT EXISTS refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    event_id UUID NOT NULL REFERENCES events(id),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    usdt_amount NUMERIC(78,0) NOT NULL,
+    chain_id BIGINT DEFAULT 97,
+    token VARCHAR(10) DEFAULT 'USDT',
+    token_amount NUMERIC(78,0),
+    token_decimals INTEGER DEFAULT 6,
+    to_address VARCHAR(42),
+    reason TEXT,
+    status VARCHAR(20) DEFAULT 'pending',
+    tx_hash VARCHAR(66),
+    refunded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Seats table
+CREATE TABLE IF NOT EXISTS seats (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL REFERENCES events(id),
+    section VARCHAR(100) NOT NULL,
+    row VARCHAR(20) NOT NULL,
+    number INTEGER NOT NULL,
+    status VARCHAR(20) DEFAULT 'available',
+    transaction_id UUID REFERENCES transactions(id),
+    held_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (event_id, section, row, number)
+);
+
+-- Waitlist entries table
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL REFERENCES events(id),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    quantity INTEGER NOT NULL,
+    status VARCHAR(20) DEFAULT 'waiting',
+    offered_at TIMESTAMP WITH TIME ZONE,
+    offer_expires_at TIMESTAMP WITH TIME ZONE,
+    transaction_id UUID REFERENCES transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Quotes table
+CREATE TABLE IF NOT EXISTS quotes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL REFERENCES events(id),
+    quantity INTEGER NOT NULL,
+    total_idr BIGINT NOT NULL,
+    promo_code VARCHAR(64),
+    discount_idr BIGINT DEFAULT 0,
+    base_idr BIGINT DEFAULT 0,
+    fee_idr BIGINT DEFAULT 0,
+    network_fee_idr BIGINT DEFAULT 0,
+    charged_idr BIGINT DEFAULT 0,
+    fee_rule_id UUID,
+    fee_rule TEXT,
+    rate_id UUID NOT NULL,
+    usdt_rate NUMERIC(20,8) NOT NULL,
+    usdt_amount NUMERIC(78,0) NOT NULL,
+    chain_id BIGINT DEFAULT 97,
+    payment_token VARCHAR(10) DEFAULT 'USDT',
+    token_rate NUMERIC(20,8),
+    token_amount NUMERIC(78,0),
+    token_decimals INTEGER DEFAULT 6,
+    platform_fee_percent DECIMAL(5,2),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    transaction_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Hourly rate candles (downsampled usdt_rates)
+CREATE TABLE IF NOT EXISTS rate_candles (
+    bucket_start TIMESTAMP WITH TIME ZONE PRIMARY KEY,
+    open DECIMAL(15,6) NOT NULL,
+    high DECIMAL(15,6) NOT NULL,
+    low DECIMAL(15,6) NOT NULL,
+    close DECIMAL(15,6) NOT NULL,
+    samples INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Manual rate overrides
+CREATE TABLE IF NOT EXISTS rate_overrides (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rate_id UUID NOT NULL REFERENCES usdt_rates(id),
+    rate DECIMAL(15,6) NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) DEFAULT 'active',
+    created_by VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Rate override audit trail
+CREATE TABLE IF NOT EXISTS rate_audit_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action VARCHAR(50) NOT NULL,
+    override_id UUID REFERENCES rate_overrides(id),
+    actor VARCHAR(255) NOT NULL,
+    claimed_by VARCHAR(255),
+    remote_ip VARCHAR(64),
+    details TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Platform fee rules
+CREATE TABLE IF NOT EXISTS fee_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    scope VARCHAR(20) NOT NULL,
+    event_id UUID REFERENCES events(id),
+    organizer VARCHAR(255),
+    percent_bps BIGINT NOT NULL DEFAULT 0,
+    fixed_idr BIGINT NOT NULL DEFAULT 0,
+    min_idr BIGINT NOT NULL DEFAULT 0,
+    max_idr BIGINT NOT NULL DEFAULT 0,
+    pass_through_network_fee BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) DEFAULT 'active',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Promo codes
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code VARCHAR(64) UNIQUE NOT NULL,
+    description TEXT,
+    discount_type VARCHAR(20) NOT NULL,
+    percent_bps BIGINT NOT NULL DEFAULT 0,
+    amount_idr BIGINT NOT NULL DEFAULT 0,
+    max_discount_idr BIGINT NOT NULL DEFAULT 0,
+    min_order_idr BIGINT NOT NULL DEFAULT 0,
+    event_id UUID REFERENCES events(id),
+    max_redemptions INTEGER NOT NULL DEFAULT 0,
+    max_per_customer INTEGER NOT NULL DEFAULT 0,
+    redeemed_count INTEGER NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    status VARCHAR(20) DEFAULT 'active',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Promo code redemptions, one per transaction
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    promo_code_id UUID NOT NULL REFERENCES promo_codes(id),
+    transaction_id UUID UNIQUE NOT NULL REFERENCES transactions(id),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    discount_idr BIGINT NOT NULL,
+    status VARCHAR(20) DEFAULT 'redeemed',
+    released_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Webhook subscriptions
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    url TEXT NOT NULL,
+    description TEXT,
+    events TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    status VARCHAR(20) DEFAULT 'active',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Webhook delivery queue and log
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id),
+    event_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending',
+    attempts INTEGER DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    last_status_code INTEGER,
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    redelivery_of UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Individual webhook delivery attempts
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    delivery_id UUID NOT NULL REFERENCES webhook_deliveries(id),
+    attempt INTEGER NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    response_body TEXT,
+    duration_ms BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Events written together with the change they describe, published by the outbox relay
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id UUID PRIMARY KEY,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending',
+    published_sinks TEXT,
+    attempts INTEGER DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    published_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Manual payment checks and what they found
+CREATE TABLE IF NOT EXISTS payment_checks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    chain_id BIGINT,
+    status VARCHAR(20) DEFAULT 'running',
+    result VARCHAR(30),
+    from_block BIGINT,
+    to_block BIGINT,
+    confirmed_block BIGINT,
+    blocks_scanned INTEGER DEFAULT 0,
+    candidates TEXT,
+    tx_hash VARCHAR(66),
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Customer emails, rendered when queued; also the log of what was sent
+CREATE TABLE IF NOT EXISTS email_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    dedupe_key VARCHAR(255) UNIQUE NOT NULL,
+    kind VARCHAR(50) NOT NULL,
+    transaction_id UUID REFERENCES transactions(id),
+    customer_id UUID REFERENCES customers(id),
+    to_address VARCHAR(255) NOT NULL,
+    to_name VARCHAR(255),
+    locale VARCHAR(10),
+    subject TEXT NOT NULL,
+    text_body TEXT,
+    html_body TEXT,
+    attachments TEXT,
+    status VARCHAR(20) DEFAULT 'pending',
+    attempts INTEGER DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- API keys for server-to-server clients; only a hash of each key is stored
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    organizer VARCHAR(255),
+    prefix VARCHAR(20) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    created_by VARCHAR(255),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 -- Create indexes for performance
 CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events(deleted_at);
 CREATE INDEX IF NOT EXISTS idx_events_schedule ON events(schedule);
+CREATE INDEX IF NOT EXISTS idx_events_search ON events USING GIN (to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(description, '')));
+CREATE INDEX IF NOT EXISTS idx_events_schedule_id ON events(schedule, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_events_price_id ON events(price_idr, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_events_created_at_id ON events(created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_events_location_trgm ON events USING GIN (location gin_trgm_ops);
 CREATE INDEX IF NOT EXISTS idx_customers_email ON customers(email);
 CREATE INDEX IF NOT EXISTS idx_transactions_status ON transactions(status);
 CREATE INDEX IF NOT EXISTS idx_transactions_payment_address ON transactions(payment_address);
+CREATE INDEX IF NOT EXISTS idx_transactions_payment_deadline ON transactions(payment_deadline);
 CREATE INDEX IF NOT EXISTS idx_tickets_ticket_code ON tickets(ticket_code);
 CREATE INDEX IF NOT EXISTS idx_payment_addresses_is_used ON payment_addresses(is_used);
 CREATE INDEX IF NOT EXISTS idx_usdt_rates_created_at ON usdt_rates(created_at);
 CREATE INDEX IF NOT EXISTS idx_blockchain_transactions_tx_hash ON blockchain_transactions(tx_hash);
 CREATE INDEX IF NOT EXISTS idx_blockchain_transactions_status ON blockchain_transactions(status);
+CREATE INDEX IF NOT EXISTS idx_refunds_transaction_id ON refunds(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_event_id ON refunds(event_id);
+CREATE INDEX IF NOT EXISTS idx_seats_status ON seats(status);
+CREATE INDEX IF NOT EXISTS idx_seats_transaction_id ON seats(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_waitlist_event_status ON waitlist_entries(event_id, status);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_customer_id ON waitlist_entries(customer_id);
+CREATE INDEX IF NOT EXISTS idx_quotes_event_id ON quotes(event_id);
+CREATE INDEX IF NOT EXISTS idx_quotes_expires_at ON quotes(expires_at);
+CREATE INDEX IF NOT EXISTS idx_transactions_chain_id ON transactions(chain_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_pending_amount ON transactions(chain_id, payment_address, payment_token, token_amount) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_events_organizer ON events(organizer);
+CREATE INDEX IF NOT EXISTS idx_fee_rules_scope ON fee_rules(scope);
+CREATE INDEX IF NOT EXISTS idx_fee_rules_event_id ON fee_rules(event_id);
+CREATE INDEX IF NOT EXISTS idx_fee_rules_organizer ON fee_rules(organizer);
+CREATE INDEX IF NOT EXISTS idx_fee_rules_status ON fee_rules(status);
+CREATE INDEX IF NOT EXISTS idx_transactions_promo_code_id ON transactions(promo_code_id);
+CREATE INDEX IF NOT EXISTS idx_promo_codes_event_id ON promo_codes(event_id);
+CREATE INDEX IF NOT EXISTS idx_promo_codes_status ON promo_codes(status);
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_promo_code_id ON promo_redemptions(promo_code_id);
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_customer_id ON promo_redemptions(customer_id);
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_status ON promo_redemptions(status);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_status ON webhook_subscriptions(status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries(subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries(event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery_id ON webhook_attempts(delivery_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_aggregate ON outbox_events(aggregate_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_due ON outbox_events(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_payment_checks_transaction_id ON payment_checks(transaction_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_checks_running ON payment_checks(transaction_id) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_email_messages_kind ON email_messages(kind);
+CREATE INDEX IF NOT EXISTS idx_email_messages_transaction_id ON email_messages(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_email_messages_due ON email_messages(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_api_keys_organizer ON api_keys(organizer);
+CREATE INDEX IF NOT EXISTS idx_rate_overrides_status ON rate_overrides(status);
+CREATE INDEX IF NOT EXISTS idx_rate_audit_logs_action ON rate_audit_logs(action);
+CREATE INDEX IF NOT EXISTS idx_rate_audit_logs_override_id ON rate_audit_logs(override_id);
+CREATE INDEX IF NOT EXISTS idx_rate_audit_logs_created_at ON rate_audit_logs(created_at);
 
 -- Insert sample data for testing
 
@@ -137,4 +506,4 @@ BEGIN
     RAISE NOTICE 'Schema created with sample data for testing.';
     RAISE NOTICE 'Use the seeder command to generate actual payment addresses: go run cmd/seeder/main.go';
 END
-$$;
\ No newline at end of file
+$$;